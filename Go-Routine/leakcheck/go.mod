@@ -0,0 +1,3 @@
+module dev.mfr/go-routine/leakcheck
+
+go 1.24.5
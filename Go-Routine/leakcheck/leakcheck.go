@@ -0,0 +1,185 @@
+// Package leakcheck fails tests that leave goroutines running.
+//
+// Call Check at the start of a test, or VerifyTestMain from TestMain to
+// cover a whole package:
+//
+//	func TestHandler(t *testing.T) {
+//		leakcheck.Check(t)
+//		...
+//	}
+package leakcheck
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Timeout is how long Check and VerifyTestMain wait for goroutines to exit
+// before reporting them, since shutdown is often asynchronous.
+var Timeout = 2 * time.Second
+
+// ignoredFunctions are parts of stacks that belong to the runtime, the
+// testing package or long-lived standard library helpers rather than to the
+// code under test.
+var ignoredFunctions = []string{
+	"testing.RunTests",
+	"testing.(*T).Run",
+	"testing.(*T).Parallel",
+	"testing.runFuzzing",
+	"testing.tRunner.func1",
+	"testing.(*M).startAlarm",
+	"runtime.goexit0",
+	"runtime.ensureSigM",
+	"os/signal.signal_recv",
+	"os/signal.loop",
+	"runtime.ReadTrace",
+	"runtime/trace.Start",
+	"created by runtime.gc",
+	"created by runtime/pprof",
+}
+
+// Goroutine is one entry of a goroutine dump.
+type Goroutine struct {
+	ID    int
+	State string
+	// Stack is the full dump of the goroutine, header included.
+	Stack string
+}
+
+// Snapshot is the set of goroutines running at some point.
+type Snapshot map[int]bool
+
+// Take records the goroutines running now.
+func Take() Snapshot {
+	s := make(Snapshot)
+	for _, g := range running() {
+		s[g.ID] = true
+	}
+	return s
+}
+
+// Leaks waits up to Timeout for every goroutine started since s to exit and
+// returns the ones still running, leaving out those whose stack contains one
+// of ignore or a known runtime or testing function.
+func (s Snapshot) Leaks(ignore ...string) []Goroutine {
+	deadline := time.Now().Add(Timeout)
+	delay := time.Millisecond
+	for {
+		leaks := s.leaks(ignore)
+		if len(leaks) == 0 || time.Now().After(deadline) {
+			return leaks
+		}
+		time.Sleep(delay)
+		delay = min(2*delay, 100*time.Millisecond)
+	}
+}
+
+func (s Snapshot) leaks(ignore []string) []Goroutine {
+	var leaks []Goroutine
+	for _, g := range running() {
+		if s[g.ID] || g.ignored(ignore) {
+			continue
+		}
+		leaks = append(leaks, g)
+	}
+	return leaks
+}
+
+func (g Goroutine) ignored(extra []string) bool {
+	for _, list := range [][]string{ignoredFunctions, extra} {
+		for _, fn := range list {
+			if strings.Contains(g.Stack, fn) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Check snapshots the running goroutines and, when t finishes, fails it with
+// the stack of every goroutine started since that is still running. ignore
+// lists extra function names, or other parts of a stack, to tolerate.
+func Check(t testing.TB, ignore ...string) {
+	t.Helper()
+	before := Take()
+	t.Cleanup(func() {
+		if t.Failed() {
+			return
+		}
+		if leaks := before.Leaks(ignore...); len(leaks) > 0 {
+			t.Errorf("found %d leaked goroutines:\n\n%s", len(leaks), Format(leaks))
+		}
+	})
+}
+
+// VerifyTestMain runs the tests of m and exits with a failure status if any
+// goroutine they started is still running afterwards.
+func VerifyTestMain(m *testing.M, ignore ...string) {
+	before := Take()
+	code := m.Run()
+	if code == 0 {
+		if leaks := before.Leaks(ignore...); len(leaks) > 0 {
+			fmt.Fprintf(os.Stderr, "leakcheck: found %d leaked goroutines:\n\n%s\n", len(leaks), Format(leaks))
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
+
+// Format renders goroutines the way a panic dump does.
+func Format(goroutines []Goroutine) string {
+	stacks := make([]string, len(goroutines))
+	for i, g := range goroutines {
+		stacks[i] = g.Stack
+	}
+	return strings.Join(stacks, "\n\n")
+}
+
+// running returns every goroutine except the calling one.
+func running() []Goroutine {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	dumps := bytes.Split(buf, []byte("\n\n"))
+	goroutines := make([]Goroutine, 0, len(dumps))
+	// The first dump is always the calling goroutine.
+	for _, dump := range dumps[1:] {
+		if g, ok := parse(string(dump)); ok {
+			goroutines = append(goroutines, g)
+		}
+	}
+	return goroutines
+}
+
+// parse reads a dump starting with a header like
+// "goroutine 7 [chan receive, 2 minutes]:".
+func parse(dump string) (Goroutine, bool) {
+	header, _, _ := strings.Cut(dump, "\n")
+	rest, ok := strings.CutPrefix(header, "goroutine ")
+	if !ok {
+		return Goroutine{}, false
+	}
+	idStr, state, ok := strings.Cut(rest, " ")
+	if !ok {
+		return Goroutine{}, false
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return Goroutine{}, false
+	}
+	state = strings.TrimSuffix(strings.TrimPrefix(state, "["), "]:")
+	state, _, _ = strings.Cut(state, ",")
+	return Goroutine{ID: id, State: state, Stack: strings.TrimSpace(dump)}, true
+}
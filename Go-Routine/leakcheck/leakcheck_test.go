@@ -0,0 +1,62 @@
+package leakcheck
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func leakyWorker(stop <-chan struct{}) {
+	<-stop
+}
+
+func TestLeaksFindsBlockedGoroutine(t *testing.T) {
+	defer func(d time.Duration) { Timeout = d }(Timeout)
+	Timeout = 50 * time.Millisecond
+
+	before := Take()
+	stop := make(chan struct{})
+	go leakyWorker(stop)
+
+	leaks := before.Leaks()
+	if len(leaks) != 1 {
+		t.Fatalf("Leaks() = %d goroutines, want 1:\n%s", len(leaks), Format(leaks))
+	}
+	if !strings.Contains(leaks[0].Stack, "leakyWorker") || leaks[0].State != "chan receive" {
+		t.Errorf("leak = %q in state %q, want leakyWorker in \"chan receive\"", leaks[0].Stack, leaks[0].State)
+	}
+	if ignored := before.Leaks("leakcheck.leakyWorker"); len(ignored) != 0 {
+		t.Errorf("Leaks(ignoring leakyWorker) = %d goroutines, want 0", len(ignored))
+	}
+
+	close(stop)
+	if leaks := before.Leaks(); len(leaks) != 0 {
+		t.Errorf("Leaks() after stopping the worker = %d goroutines, want 0:\n%s", len(leaks), Format(leaks))
+	}
+}
+
+func TestLeaksWaitsForExitingGoroutines(t *testing.T) {
+	before := Take()
+	go time.Sleep(20 * time.Millisecond)
+	if leaks := before.Leaks(); len(leaks) != 0 {
+		t.Errorf("Leaks() = %d goroutines, want 0 once the sleeper exits", len(leaks))
+	}
+}
+
+func TestCheck(t *testing.T) {
+	Check(t)
+	done := make(chan struct{})
+	go func() { close(done) }()
+	<-done
+}
+
+func TestParse(t *testing.T) {
+	dump := "goroutine 42 [select, 3 minutes]:\nmain.worker()\n\t/src/main.go:10 +0x1d"
+	g, ok := parse(dump)
+	if !ok || g.ID != 42 || g.State != "select" || g.Stack != dump {
+		t.Errorf("parse = %+v, %v, want ID 42 in state \"select\"", g, ok)
+	}
+	if _, ok := parse("not a goroutine"); ok {
+		t.Errorf("parse(garbage) = ok, want !ok")
+	}
+}
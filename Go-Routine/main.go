@@ -2,23 +2,40 @@ package main
 
 import (
 	"fmt"
-	"runtime"
-	"time"
+	"os"
 )
 
-func main() {
-	runtime.GOMAXPROCS(2)
+const usage = `Usage:
+  go-routine pool [flags]       run tasks on a bounded worker pool
+  go-routine scenario [flags]   benchmark scheduler behavior across workloads
+  go-routine pipeline [flags]   run a multi-stage fan-out/fan-in pipeline
 
-	go print(5, "Hello from Goroutine")
-	print(5, "Hello from Main")
+Run "go-routine <command> -h" for the flags of a command.
+`
 
-	time.Sleep(1 * time.Second)
-	fmt.Println("All Goroutines finished")
-}
-func print(till int, message string) {
-	for i := 1; i <= till; i++ {
-		fmt.Println("current Active Goroutines:", runtime.NumGoroutine())
-		fmt.Println(message, i)
-		// time.Sleep(250 * time.Millisecond)
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "pool":
+		err = runPool(os.Args[2:])
+	case "scenario":
+		err = runScenarios(os.Args[2:])
+	case "pipeline":
+		err = runPipeline(os.Args[2:])
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,263 @@
+// Package pipeline provides generic, context-aware stages connected by
+// bounded channels: Generator, Map, Filter, FanOut, FanIn, Batch and
+// Throttle.
+//
+// Every stage belongs to a Pipeline. The first stage to fail cancels the
+// pipeline, which stops all other stages, and Wait reports that error. A
+// consumer that stops reading the last channel early must call Stop, or the
+// stages feeding it block until the parent context is done.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrStopped is returned by Wait after Stop.
+var ErrStopped = errors.New("pipeline stopped")
+
+// Pipeline tracks the goroutines of its stages and the first error.
+type Pipeline struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+	buffer int
+}
+
+// New returns a pipeline whose stages stop when ctx is done. Every channel
+// between stages holds at most buffer items, so a slow stage applies
+// backpressure to the ones before it.
+func New(ctx context.Context, buffer int) *Pipeline {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Pipeline{ctx: ctx, cancel: cancel, buffer: max(buffer, 0)}
+}
+
+// Context returns the context stage functions receive. It is canceled when
+// any stage fails.
+func (p *Pipeline) Context() context.Context {
+	return p.ctx
+}
+
+// Stop cancels every stage.
+func (p *Pipeline) Stop() {
+	p.cancel(ErrStopped)
+}
+
+// Wait waits for every stage to return and reports the first error, or the
+// parent context's error if it was canceled.
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	err := context.Cause(p.ctx)
+	p.cancel(nil)
+	return err
+}
+
+// fail cancels the pipeline with err; only the first error is kept.
+func (p *Pipeline) fail(err error) {
+	p.cancel(err)
+}
+
+// stage runs fn on a tracked goroutine, closing out when it returns.
+func stage[T any](p *Pipeline, fn func(out chan<- T) error) <-chan T {
+	out := make(chan T, p.buffer)
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer close(out)
+		if err := fn(out); err != nil {
+			p.fail(err)
+		}
+	}()
+	return out
+}
+
+// send delivers v unless the pipeline is canceled first.
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Generator produces the items fn emits. emit returns false once the
+// pipeline is canceled, at which point fn should return.
+func Generator[T any](p *Pipeline, fn func(ctx context.Context, emit func(T) bool) error) <-chan T {
+	return stage(p, func(out chan<- T) error {
+		return fn(p.ctx, func(v T) bool { return send(p.ctx, out, v) })
+	})
+}
+
+// FromSlice produces the items of items in order.
+func FromSlice[T any](p *Pipeline, items []T) <-chan T {
+	return Generator(p, func(_ context.Context, emit func(T) bool) error {
+		for _, v := range items {
+			if !emit(v) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// Map transforms every item of in with fn.
+func Map[T, R any](p *Pipeline, in <-chan T, fn func(context.Context, T) (R, error)) <-chan R {
+	return stage(p, func(out chan<- R) error {
+		for v := range in {
+			r, err := fn(p.ctx, v)
+			if err != nil {
+				return err
+			}
+			if !send(p.ctx, out, r) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// Filter passes on the items of in for which keep returns true.
+func Filter[T any](p *Pipeline, in <-chan T, keep func(context.Context, T) (bool, error)) <-chan T {
+	return stage(p, func(out chan<- T) error {
+		for v := range in {
+			ok, err := keep(p.ctx, v)
+			if err != nil {
+				return err
+			}
+			if ok && !send(p.ctx, out, v) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// FanOut splits in across n channels; each item goes to exactly one of them,
+// whichever is ready first. Attach a stage to every channel to process items
+// in parallel.
+func FanOut[T any](p *Pipeline, in <-chan T, n int) []<-chan T {
+	outs := make([]<-chan T, max(n, 1))
+	for i := range outs {
+		outs[i] = stage(p, func(out chan<- T) error {
+			for v := range in {
+				if !send(p.ctx, out, v) {
+					return nil
+				}
+			}
+			return nil
+		})
+	}
+	return outs
+}
+
+// FanIn merges ins into one channel, in no particular order. It is closed
+// once every input is.
+func FanIn[T any](p *Pipeline, ins ...<-chan T) <-chan T {
+	out := make(chan T, p.buffer)
+	var wg sync.WaitGroup
+	for _, in := range ins {
+		wg.Add(1)
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			defer wg.Done()
+			for v := range in {
+				if !send(p.ctx, out, v) {
+					return
+				}
+			}
+		}()
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Batch groups the items of in into slices of up to size items. A partial
+// batch is sent once maxWait has passed since its first item, or when in is
+// closed; a zero maxWait only sends full batches until then.
+func Batch[T any](p *Pipeline, in <-chan T, size int, maxWait time.Duration) <-chan []T {
+	size = max(size, 1)
+	return stage(p, func(out chan<- []T) error {
+		var batch []T
+		var timeout <-chan time.Time
+		var timer *time.Timer
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer, timeout = nil, nil
+			}
+			if len(batch) == 0 {
+				return true
+			}
+			ok := send(p.ctx, out, batch)
+			batch = nil
+			return ok
+		}
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					flush()
+					return nil
+				}
+				batch = append(batch, v)
+				if len(batch) == 1 && maxWait > 0 {
+					timer = time.NewTimer(maxWait)
+					timeout = timer.C
+				}
+				if len(batch) == size && !flush() {
+					return nil
+				}
+			case <-timeout:
+				timer, timeout = nil, nil
+				if !flush() {
+					return nil
+				}
+			case <-p.ctx.Done():
+				return nil
+			}
+		}
+	})
+}
+
+// Throttle passes on the items of in, letting at least every pass between
+// two of them.
+func Throttle[T any](p *Pipeline, in <-chan T, every time.Duration) <-chan T {
+	return stage(p, func(out chan<- T) error {
+		var next time.Time
+		for v := range in {
+			if wait := time.Until(next); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-p.ctx.Done():
+					timer.Stop()
+					return nil
+				}
+			}
+			if !send(p.ctx, out, v) {
+				return nil
+			}
+			next = time.Now().Add(every)
+		}
+		return nil
+	})
+}
+
+// Collect reads every item of in, waits for the pipeline and returns the
+// items along with Wait's error.
+func Collect[T any](p *Pipeline, in <-chan T) ([]T, error) {
+	var items []T
+	for v := range in {
+		items = append(items, v)
+	}
+	return items, p.Wait()
+}
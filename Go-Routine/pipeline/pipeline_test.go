@@ -0,0 +1,138 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func numbers(n int) []int {
+	items := make([]int, n)
+	for i := range items {
+		items[i] = i + 1
+	}
+	return items
+}
+
+func TestMapFilter(t *testing.T) {
+	p := New(context.Background(), 2)
+	even := Filter(p, FromSlice(p, numbers(10)), func(_ context.Context, n int) (bool, error) {
+		return n%2 == 0, nil
+	})
+	squares := Map(p, even, func(_ context.Context, n int) (int, error) {
+		return n * n, nil
+	})
+	got, err := Collect(p, squares)
+	if want := []int{4, 16, 36, 64, 100}; !slices.Equal(got, want) || err != nil {
+		t.Fatalf("Collect = %v, %v, want %v, nil", got, err, want)
+	}
+}
+
+func TestFanOutFanIn(t *testing.T) {
+	p := New(context.Background(), 4)
+	var workers []<-chan int
+	for _, ch := range FanOut(p, FromSlice(p, numbers(100)), 4) {
+		workers = append(workers, Map(p, ch, func(_ context.Context, n int) (int, error) {
+			return n * 2, nil
+		}))
+	}
+	got, err := Collect(p, FanIn(p, workers...))
+	if err != nil || len(got) != 100 {
+		t.Fatalf("Collect = %d items, %v, want 100, nil", len(got), err)
+	}
+	slices.Sort(got)
+	for i, v := range got {
+		if v != (i+1)*2 {
+			t.Fatalf("sorted results[%d] = %d, want %d", i, v, (i+1)*2)
+		}
+	}
+}
+
+func TestErrorCancelsPipeline(t *testing.T) {
+	errBad := errors.New("bad item")
+	p := New(context.Background(), 1)
+	infinite := Generator(p, func(_ context.Context, emit func(int) bool) error {
+		for i := 0; ; i++ {
+			if !emit(i) {
+				return nil
+			}
+		}
+	})
+	failing := Map(p, infinite, func(_ context.Context, n int) (int, error) {
+		if n == 5 {
+			return 0, errBad
+		}
+		return n, nil
+	})
+	got, err := Collect(p, failing)
+	if !errors.Is(err, errBad) {
+		t.Fatalf("Collect error = %v, want errBad", err)
+	}
+	if len(got) > 5 {
+		t.Errorf("Collect = %v, want at most the 5 items before the failure", got)
+	}
+}
+
+func TestStop(t *testing.T) {
+	p := New(context.Background(), 0)
+	out := Generator(p, func(_ context.Context, emit func(int) bool) error {
+		for emit(1) {
+		}
+		return nil
+	})
+	<-out
+	p.Stop()
+	for range out {
+	}
+	if err := p.Wait(); !errors.Is(err, ErrStopped) {
+		t.Fatalf("Wait after Stop = %v, want ErrStopped", err)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	p := New(context.Background(), 0)
+	got, err := Collect(p, Batch(p, FromSlice(p, numbers(7)), 3, 0))
+	want := [][]int{{1, 2, 3}, {4, 5, 6}, {7}}
+	if err != nil || !slices.EqualFunc(got, want, slices.Equal) {
+		t.Fatalf("Collect(Batch) = %v, %v, want %v, nil", got, err, want)
+	}
+}
+
+func TestBatchMaxWait(t *testing.T) {
+	p := New(context.Background(), 0)
+	slow := Generator(p, func(ctx context.Context, emit func(int) bool) error {
+		emit(1)
+		time.Sleep(50 * time.Millisecond)
+		emit(2)
+		return nil
+	})
+	got, err := Collect(p, Batch(p, slow, 10, 10*time.Millisecond))
+	want := [][]int{{1}, {2}}
+	if err != nil || !slices.EqualFunc(got, want, slices.Equal) {
+		t.Fatalf("Collect(Batch) = %v, %v, want %v, nil", got, err, want)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	p := New(context.Background(), 0)
+	start := time.Now()
+	got, err := Collect(p, Throttle(p, FromSlice(p, numbers(4)), 10*time.Millisecond))
+	if err != nil || len(got) != 4 {
+		t.Fatalf("Collect(Throttle) = %v, %v, want 4 items, nil", got, err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("4 throttled items took %s, want at least 30ms", elapsed)
+	}
+}
+
+func TestParentCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := New(ctx, 0)
+	_, err := Collect(p, FromSlice(p, numbers(10)))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Collect with canceled parent = %v, want context.Canceled", err)
+	}
+}
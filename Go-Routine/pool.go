@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"runtime"
+	"time"
+
+	"dev.mfr/go-routine/workpool"
+)
+
+// runPool squares numbers on a worker pool, optionally failing or panicking
+// on some of them, and prints every result plus the aggregated error.
+func runPool(args []string) error {
+	fs := flag.NewFlagSet("pool", flag.ContinueOnError)
+	procs := fs.Int("gomaxprocs", 2, "value for runtime.GOMAXPROCS")
+	workers := fs.Int("workers", 4, "number of worker goroutines")
+	tasks := fs.Int("tasks", 20, "number of tasks")
+	work := fs.Duration("work", 10*time.Millisecond, "how long each task sleeps")
+	completion := fs.Bool("completion-order", false, "print results as tasks finish instead of in input order")
+	failEvery := fs.Int("fail-every", 0, "make every n-th task return an error (0 disables)")
+	panicEvery := fs.Int("panic-every", 0, "make every n-th task panic (0 disables)")
+	failFast := fs.Bool("fail-fast", false, "cancel the remaining tasks after the first failure")
+	timeout := fs.Duration("timeout", 0, "cancel the run after this long (0 disables)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	runtime.GOMAXPROCS(*procs)
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	cfg := workpool.Config{Workers: *workers, FailFast: *failFast}
+	if *completion {
+		cfg.Order = workpool.CompletionOrder
+	}
+	pool := workpool.New(cfg, func(ctx context.Context, n int) (int, error) {
+		select {
+		case <-time.After(*work):
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+		if *panicEvery > 0 && n%*panicEvery == 0 {
+			panic(fmt.Sprintf("task %d panicked", n))
+		}
+		if *failEvery > 0 && n%*failEvery == 0 {
+			return 0, fmt.Errorf("task %d failed", n)
+		}
+		return n * n, nil
+	})
+
+	inputs := make([]int, *tasks)
+	for i := range inputs {
+		inputs[i] = i + 1
+	}
+
+	start := time.Now()
+	results, err := pool.Run(ctx, inputs)
+	elapsed := time.Since(start)
+
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Printf("task %2d: error: %v\n", r.Input, r.Err)
+			continue
+		}
+		fmt.Printf("task %2d: %d\n", r.Input, r.Value)
+	}
+	fmt.Printf("%d tasks on %d workers (GOMAXPROCS=%d) in %s, %d goroutines still running\n",
+		len(inputs), *workers, runtime.GOMAXPROCS(0), elapsed.Round(time.Millisecond), runtime.NumGoroutine())
+	if err != nil {
+		return fmt.Errorf("some tasks failed:\n%w", err)
+	}
+	fmt.Println("All Goroutines finished")
+	return nil
+}
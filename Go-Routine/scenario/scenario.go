@@ -0,0 +1,265 @@
+// Package scenario runs timed concurrency workloads and collects scheduler
+// statistics: throughput, latency percentiles and goroutine high-water marks.
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Workload is the kind of work each goroutine of a scenario repeats.
+type Workload string
+
+const (
+	// CPU spins on arithmetic, competing for Ps.
+	CPU Workload = "cpu"
+	// Sleep parks the goroutine on a timer, so it hardly needs a P.
+	Sleep Workload = "sleep"
+	// PingPong bounces a token between pairs of goroutines over unbuffered
+	// channels, measuring hand-off latency.
+	PingPong Workload = "pingpong"
+)
+
+// Workloads lists every supported workload.
+var Workloads = []Workload{CPU, Sleep, PingPong}
+
+// Config describes one scenario.
+type Config struct {
+	// GOMAXPROCS is applied for the duration of the run. Zero keeps the
+	// current value.
+	GOMAXPROCS int
+	Goroutines int
+	Workload   Workload
+	Duration   time.Duration
+	// SleepFor is the length of one Sleep operation. Zero means 1ms.
+	SleepFor time.Duration
+	// CPUIterations is the size of one CPU operation. Zero means 10000.
+	CPUIterations int
+}
+
+// Stats is the outcome of a scenario.
+type Stats struct {
+	Workload   Workload      `json:"workload"`
+	GOMAXPROCS int           `json:"gomaxprocs"`
+	Goroutines int           `json:"goroutines"`
+	Elapsed    time.Duration `json:"elapsed_ns"`
+	Ops        int64         `json:"ops"`
+	// Throughput is operations per second across all goroutines.
+	Throughput float64       `json:"ops_per_sec"`
+	P50        time.Duration `json:"p50_ns"`
+	P90        time.Duration `json:"p90_ns"`
+	P99        time.Duration `json:"p99_ns"`
+	Max        time.Duration `json:"max_ns"`
+	// HighWater is the largest runtime.NumGoroutine() seen during the run.
+	HighWater int `json:"goroutine_high_water"`
+}
+
+// samplesPerGoroutine bounds the latency samples kept by each goroutine;
+// beyond it reservoir sampling keeps a uniform subset.
+const samplesPerGoroutine = 10000
+
+// runMu serializes runs, since GOMAXPROCS and the goroutine count are
+// process-wide.
+var runMu sync.Mutex
+
+// Run executes cfg until its duration elapses or ctx is done.
+func Run(ctx context.Context, cfg Config) (Stats, error) {
+	if cfg.Goroutines <= 0 {
+		return Stats{}, fmt.Errorf("goroutines must be positive, got %d", cfg.Goroutines)
+	}
+	if cfg.Duration <= 0 {
+		return Stats{}, fmt.Errorf("duration must be positive, got %s", cfg.Duration)
+	}
+	if cfg.SleepFor <= 0 {
+		cfg.SleepFor = time.Millisecond
+	}
+	if cfg.CPUIterations <= 0 {
+		cfg.CPUIterations = 10000
+	}
+	var body func(ctx context.Context, record func(time.Duration))
+	switch cfg.Workload {
+	case CPU:
+		body = func(ctx context.Context, record func(time.Duration)) {
+			for ctx.Err() == nil {
+				start := time.Now()
+				spin(cfg.CPUIterations)
+				record(time.Since(start))
+			}
+		}
+	case Sleep:
+		body = func(ctx context.Context, record func(time.Duration)) {
+			for ctx.Err() == nil {
+				start := time.Now()
+				time.Sleep(cfg.SleepFor)
+				record(time.Since(start))
+			}
+		}
+	case PingPong:
+		// handled by runPingPong
+	default:
+		return Stats{}, fmt.Errorf("unknown workload %q", cfg.Workload)
+	}
+
+	runMu.Lock()
+	defer runMu.Unlock()
+	if cfg.GOMAXPROCS > 0 {
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(cfg.GOMAXPROCS))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	stopSampler := make(chan struct{})
+	highWater := make(chan int)
+	go sampleGoroutines(stopSampler, highWater)
+
+	recorders := make([]*recorder, cfg.Goroutines)
+	for i := range recorders {
+		recorders[i] = newRecorder(uint64(i))
+	}
+	start := time.Now()
+	var wg sync.WaitGroup
+	if cfg.Workload == PingPong {
+		runPingPong(ctx, &wg, recorders)
+	} else {
+		for _, r := range recorders {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				body(ctx, r.record)
+			}()
+		}
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	close(stopSampler)
+
+	stats := Stats{
+		Workload:   cfg.Workload,
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Goroutines: cfg.Goroutines,
+		Elapsed:    elapsed,
+		HighWater:  <-highWater,
+	}
+	var samples []time.Duration
+	for _, r := range recorders {
+		stats.Ops += r.ops
+		samples = append(samples, r.samples...)
+	}
+	stats.Throughput = float64(stats.Ops) / elapsed.Seconds()
+	slices.Sort(samples)
+	stats.P50 = percentile(samples, 50)
+	stats.P90 = percentile(samples, 90)
+	stats.P99 = percentile(samples, 99)
+	if len(samples) > 0 {
+		stats.Max = samples[len(samples)-1]
+	}
+	return stats, nil
+}
+
+// runPingPong pairs up the goroutines; the first of each pair sends a token
+// and records the round trip. An odd goroutine out pings itself through a
+// buffered channel so every requested goroutine takes part.
+func runPingPong(ctx context.Context, wg *sync.WaitGroup, recorders []*recorder) {
+	for i := 0; i < len(recorders); i += 2 {
+		r := recorders[i]
+		if i+1 == len(recorders) {
+			ch := make(chan struct{}, 1)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for ctx.Err() == nil {
+					start := time.Now()
+					ch <- struct{}{}
+					<-ch
+					r.record(time.Since(start))
+				}
+			}()
+			break
+		}
+		ping, pong := make(chan struct{}), make(chan struct{})
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			defer close(ping)
+			for ctx.Err() == nil {
+				start := time.Now()
+				ping <- struct{}{}
+				<-pong
+				r.record(time.Since(start))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range ping {
+				pong <- struct{}{}
+			}
+		}()
+	}
+}
+
+// sampleGoroutines polls runtime.NumGoroutine until stop is closed, then
+// sends the highest value seen.
+func sampleGoroutines(stop <-chan struct{}, highWater chan<- int) {
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	peak := runtime.NumGoroutine()
+	for {
+		select {
+		case <-ticker.C:
+			peak = max(peak, runtime.NumGoroutine())
+		case <-stop:
+			highWater <- peak
+			return
+		}
+	}
+}
+
+// sink keeps the compiler from optimizing spin away.
+var sink atomic.Uint64
+
+func spin(iterations int) {
+	x := uint64(1)
+	for i := 0; i < iterations; i++ {
+		x = x*6364136223846793005 + 1442695040888963407
+	}
+	sink.Add(x)
+}
+
+// recorder collects the latencies of one goroutine.
+type recorder struct {
+	ops     int64
+	samples []time.Duration
+	rnd     *rand.Rand
+}
+
+func newRecorder(seed uint64) *recorder {
+	return &recorder{rnd: rand.New(rand.NewPCG(seed, 0))}
+}
+
+func (r *recorder) record(d time.Duration) {
+	r.ops++
+	if len(r.samples) < samplesPerGoroutine {
+		r.samples = append(r.samples, d)
+		return
+	}
+	if i := r.rnd.Int64N(r.ops); i < samplesPerGoroutine {
+		r.samples[i] = d
+	}
+}
+
+// percentile returns the p-th percentile of sorted using the nearest-rank
+// method, or 0 for no samples.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
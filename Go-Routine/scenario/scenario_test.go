@@ -0,0 +1,67 @@
+package scenario
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	for _, w := range Workloads {
+		for _, goroutines := range []int{1, 3} {
+			stats, err := Run(context.Background(), Config{
+				GOMAXPROCS: 2,
+				Goroutines: goroutines,
+				Workload:   w,
+				Duration:   30 * time.Millisecond,
+			})
+			if err != nil {
+				t.Fatalf("Run(%s, %d goroutines) returned error: %v", w, goroutines, err)
+			}
+			if stats.Ops == 0 || stats.Throughput <= 0 {
+				t.Errorf("Run(%s, %d goroutines) ops = %d, throughput = %f, want positive", w, goroutines, stats.Ops, stats.Throughput)
+			}
+			if !(stats.P50 <= stats.P90 && stats.P90 <= stats.P99 && stats.P99 <= stats.Max) {
+				t.Errorf("Run(%s) percentiles out of order: %+v", w, stats)
+			}
+			if stats.HighWater < goroutines {
+				t.Errorf("Run(%s) high water = %d, want at least %d", w, stats.HighWater, goroutines)
+			}
+			if stats.GOMAXPROCS != 2 {
+				t.Errorf("Run(%s) GOMAXPROCS = %d, want 2", w, stats.GOMAXPROCS)
+			}
+		}
+	}
+}
+
+func TestRunInvalid(t *testing.T) {
+	tests := []Config{
+		{Goroutines: 0, Workload: CPU, Duration: time.Millisecond},
+		{Goroutines: 1, Workload: CPU},
+		{Goroutines: 1, Workload: "disk", Duration: time.Millisecond},
+	}
+	for _, cfg := range tests {
+		if _, err := Run(context.Background(), cfg); err == nil {
+			t.Errorf("Run(%+v) returned nil error", cfg)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i + 1)
+	}
+	tests := []struct {
+		p    int
+		want time.Duration
+	}{{50, 50}, {90, 90}, {99, 99}, {100, 100}, {0, 1}}
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(1..100, %d) = %d, want %d", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("percentile(nil, 50) = %d, want 0", got)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"dev.mfr/go-routine/scenario"
+)
+
+// runScenarios runs every combination of the requested GOMAXPROCS values,
+// goroutine counts and workloads, then reports the statistics.
+func runScenarios(args []string) error {
+	fs := flag.NewFlagSet("scenario", flag.ContinueOnError)
+	procsList := fs.String("gomaxprocs", "1,2", "comma-separated GOMAXPROCS values")
+	goroutineList := fs.String("goroutines", "1,8,64", "comma-separated goroutine counts")
+	workloadList := fs.String("workload", "cpu,sleep,pingpong", "comma-separated workloads: cpu, sleep, pingpong")
+	duration := fs.Duration("duration", 500*time.Millisecond, "how long each scenario runs")
+	sleepFor := fs.Duration("sleep", time.Millisecond, "length of one sleep operation")
+	format := fs.String("format", "table", "output format: table or json")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if *format != "table" && *format != "json" {
+		return fmt.Errorf("unknown format %q, want table or json", *format)
+	}
+
+	procs, err := parseInts(*procsList)
+	if err != nil {
+		return fmt.Errorf("-gomaxprocs: %w", err)
+	}
+	goroutines, err := parseInts(*goroutineList)
+	if err != nil {
+		return fmt.Errorf("-goroutines: %w", err)
+	}
+	var workloads []scenario.Workload
+	for _, w := range strings.Split(*workloadList, ",") {
+		workloads = append(workloads, scenario.Workload(strings.TrimSpace(w)))
+	}
+
+	var results []scenario.Stats
+	for _, w := range workloads {
+		for _, p := range procs {
+			for _, g := range goroutines {
+				stats, err := scenario.Run(context.Background(), scenario.Config{
+					GOMAXPROCS: p,
+					Goroutines: g,
+					Workload:   w,
+					Duration:   *duration,
+					SleepFor:   *sleepFor,
+				})
+				if err != nil {
+					return fmt.Errorf("scenario %s/%d/%d: %w", w, p, g, err)
+				}
+				results = append(results, stats)
+			}
+		}
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "workload\tgomaxprocs\tgoroutines\tops\tops/s\tp50\tp90\tp99\tmax\thigh water\t")
+	for _, s := range results {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.0f\t%s\t%s\t%s\t%s\t%d\t\n",
+			s.Workload, s.GOMAXPROCS, s.Goroutines, s.Ops, s.Throughput,
+			s.P50, s.P90, s.P99, s.Max, s.HighWater)
+	}
+	return tw.Flush()
+}
+
+func parseInts(list string) ([]int, error) {
+	var out []int
+	for _, field := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid value %q, want a positive integer", field)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"dev.mfr/go-routine/pipeline"
+)
+
+// runPipeline generates numbers, drops the ones divisible by -skip, enriches
+// the rest on -workers parallel stages, throttles them and prints them in
+// batches.
+func runPipeline(args []string) error {
+	fs := flag.NewFlagSet("pipeline", flag.ContinueOnError)
+	items := fs.Int("items", 40, "number of items to generate")
+	skip := fs.Int("skip", 3, "drop items divisible by this number (0 keeps all)")
+	workers := fs.Int("workers", 4, "number of parallel enrichment stages")
+	work := fs.Duration("work", 20*time.Millisecond, "how long enriching one item takes")
+	failAt := fs.Int("fail-at", 0, "make the enrichment of this item fail (0 disables)")
+	rate := fs.Duration("throttle", 5*time.Millisecond, "minimum time between enriched items")
+	batchSize := fs.Int("batch", 5, "items per printed batch")
+	buffer := fs.Int("buffer", 2, "capacity of the channels between stages")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	type enriched struct {
+		N      int
+		Square int
+		Worker int
+	}
+
+	start := time.Now()
+	p := pipeline.New(context.Background(), *buffer)
+	numbers := pipeline.Generator(p, func(_ context.Context, emit func(int) bool) error {
+		for i := 1; i <= *items; i++ {
+			if !emit(i) {
+				return nil
+			}
+		}
+		return nil
+	})
+	kept := pipeline.Filter(p, numbers, func(_ context.Context, n int) (bool, error) {
+		return *skip == 0 || n%*skip != 0, nil
+	})
+	var branches []<-chan enriched
+	for worker, ch := range pipeline.FanOut(p, kept, *workers) {
+		branches = append(branches, pipeline.Map(p, ch, func(ctx context.Context, n int) (enriched, error) {
+			select {
+			case <-time.After(*work):
+			case <-ctx.Done():
+				return enriched{}, ctx.Err()
+			}
+			if n == *failAt {
+				return enriched{}, fmt.Errorf("enriching item %d failed", n)
+			}
+			return enriched{N: n, Square: n * n, Worker: worker + 1}, nil
+		}))
+	}
+	throttled := pipeline.Throttle(p, pipeline.FanIn(p, branches...), *rate)
+	batches := pipeline.Batch(p, throttled, *batchSize, 50*time.Millisecond)
+
+	for batch := range batches {
+		fmt.Printf("batch of %d:", len(batch))
+		for _, e := range batch {
+			fmt.Printf(" %d²=%d (w%d)", e.N, e.Square, e.Worker)
+		}
+		fmt.Println()
+	}
+	if err := p.Wait(); err != nil {
+		return err
+	}
+	fmt.Printf("pipeline finished in %s\n", time.Since(start).Round(time.Millisecond))
+	return nil
+}
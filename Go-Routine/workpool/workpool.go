@@ -0,0 +1,164 @@
+// Package workpool runs a function over many inputs on a fixed number of
+// goroutines, with cancellation, panic recovery and error aggregation.
+package workpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Order selects how Run arranges its results.
+type Order int
+
+const (
+	// InputOrder returns results[i] for inputs[i].
+	InputOrder Order = iota
+	// CompletionOrder returns results in the order the tasks finished.
+	CompletionOrder
+)
+
+// Config holds the settings of a Pool.
+type Config struct {
+	// Workers is the number of goroutines running tasks. Zero means
+	// runtime.GOMAXPROCS(0).
+	Workers int
+	// Order is the order of the results returned by Run.
+	Order Order
+	// FailFast cancels the remaining tasks as soon as one fails.
+	FailFast bool
+}
+
+// Result is the outcome of one task.
+type Result[T, R any] struct {
+	// Index is the position of Input in the slice given to Run.
+	Index int
+	Input T
+	Value R
+	Err   error
+}
+
+// TaskError wraps the error of the task at Index.
+type TaskError struct {
+	Index int
+	Err   error
+}
+
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("task %d: %v", e.Index, e.Err)
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is the error of a task that panicked.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Pool runs a function over inputs of type T producing results of type R.
+// A Pool holds no goroutines between calls to Run and may be reused.
+type Pool[T, R any] struct {
+	cfg Config
+	fn  func(context.Context, T) (R, error)
+}
+
+func New[T, R any](cfg Config, fn func(context.Context, T) (R, error)) *Pool[T, R] {
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.GOMAXPROCS(0)
+	}
+	return &Pool[T, R]{cfg: cfg, fn: fn}
+}
+
+// Run calls the pool's function once per input and waits for every started
+// task to return. It always returns one Result per input; tasks that never
+// started because ctx was done (or a task failed with FailFast set) carry
+// the cancellation error. The returned error joins a *TaskError for each
+// failed task, or is nil if every task succeeded.
+func (p *Pool[T, R]) Run(ctx context.Context, inputs []T) ([]Result[T, R], error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	results := make([]Result[T, R], 0, len(inputs))
+	if p.cfg.Order == InputOrder {
+		results = results[:len(inputs)]
+	}
+	started := make([]bool, len(inputs)) // set by the worker that runs task i
+	var mu sync.Mutex                    // guards results in CompletionOrder
+	collect := func(r Result[T, R]) {
+		if p.cfg.Order == InputOrder {
+			results[r.Index] = r
+			return
+		}
+		mu.Lock()
+		results = append(results, r)
+		mu.Unlock()
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(p.cfg.Workers, len(inputs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if ctx.Err() != nil {
+					continue // reported as not started below
+				}
+				started[i] = true
+				value, err := p.call(ctx, inputs[i])
+				if err != nil && p.cfg.FailFast {
+					cancel(&TaskError{Index: i, Err: err})
+				}
+				collect(Result[T, R]{Index: i, Input: inputs[i], Value: value, Err: err})
+			}
+		}()
+	}
+
+feed:
+	for i := range inputs {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	for i, ok := range started {
+		if !ok {
+			collect(Result[T, R]{Index: i, Input: inputs[i], Err: context.Cause(ctx)})
+		}
+	}
+
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil && started[r.Index] {
+			errs = append(errs, &TaskError{Index: r.Index, Err: r.Err})
+		}
+	}
+	if err := context.Cause(ctx); err != nil && !errors.As(err, new(*TaskError)) {
+		errs = append(errs, err)
+	}
+	return results, errors.Join(errs...)
+}
+
+// call runs the pool's function, turning a panic into a *PanicError.
+func (p *Pool[T, R]) call(ctx context.Context, input T) (value R, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return p.fn(ctx, input)
+}
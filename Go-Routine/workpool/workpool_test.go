@@ -0,0 +1,125 @@
+package workpool
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func square(_ context.Context, n int) (int, error) {
+	return n * n, nil
+}
+
+func TestRunInputOrder(t *testing.T) {
+	inputs := make([]int, 100)
+	for i := range inputs {
+		inputs[i] = i
+	}
+	results, err := New(Config{Workers: 8}, square).Run(context.Background(), inputs)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	for i, r := range results {
+		if r.Index != i || r.Input != i || r.Value != i*i || r.Err != nil {
+			t.Fatalf("results[%d] = %+v, want index %d, value %d", i, r, i, i*i)
+		}
+	}
+}
+
+func TestRunCompletionOrder(t *testing.T) {
+	// The first input sleeps, so with two workers it finishes last.
+	inputs := []time.Duration{50 * time.Millisecond, 0, 0, 0}
+	pool := New(Config{Workers: 2, Order: CompletionOrder}, func(_ context.Context, d time.Duration) (time.Duration, error) {
+		time.Sleep(d)
+		return d, nil
+	})
+	results, err := pool.Run(context.Background(), inputs)
+	if err != nil || len(results) != len(inputs) {
+		t.Fatalf("Run = %d results, %v, want %d, nil", len(results), err, len(inputs))
+	}
+	if last := results[len(results)-1]; last.Index != 0 {
+		t.Errorf("last result index = %d, want 0", last.Index)
+	}
+}
+
+func TestRunAggregatesErrorsAndPanics(t *testing.T) {
+	errOdd := errors.New("odd input")
+	pool := New(Config{Workers: 3}, func(_ context.Context, s string) (int, error) {
+		if s == "boom" {
+			panic("kaboom")
+		}
+		n, err := strconv.Atoi(s)
+		if err == nil && n%2 == 1 {
+			return 0, errOdd
+		}
+		return n, err
+	})
+	results, err := pool.Run(context.Background(), []string{"2", "3", "boom", "4", "x"})
+	if len(results) != 5 {
+		t.Fatalf("Run returned %d results, want 5", len(results))
+	}
+	if !errors.Is(err, errOdd) {
+		t.Errorf("Run error = %v, want it to wrap errOdd", err)
+	}
+	var panicErr *PanicError
+	if !errors.As(results[2].Err, &panicErr) || panicErr.Value != "kaboom" || len(panicErr.Stack) == 0 {
+		t.Errorf("results[2].Err = %v, want *PanicError with value \"kaboom\" and a stack", results[2].Err)
+	}
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) {
+		t.Errorf("Run error = %v, want *TaskError", err)
+	}
+	if results[0].Value != 2 || results[3].Value != 4 {
+		t.Errorf("successful results = %+v, %+v, want values 2 and 4", results[0], results[3])
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var ran atomic.Int32
+	pool := New(Config{Workers: 1}, func(ctx context.Context, n int) (int, error) {
+		if ran.Add(1) == 2 {
+			cancel()
+		}
+		return n, ctx.Err()
+	})
+	inputs := make([]int, 50)
+	results, err := pool.Run(ctx, inputs)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run error = %v, want context.Canceled", err)
+	}
+	if len(results) != len(inputs) {
+		t.Fatalf("Run returned %d results, want %d", len(results), len(inputs))
+	}
+	if n := ran.Load(); n >= int32(len(inputs)) {
+		t.Errorf("%d tasks ran after cancellation, want fewer than %d", n, len(inputs))
+	}
+	if !errors.Is(results[len(results)-1].Err, context.Canceled) {
+		t.Errorf("last result error = %v, want context.Canceled", results[len(results)-1].Err)
+	}
+}
+
+func TestRunFailFast(t *testing.T) {
+	errFirst := errors.New("first task failed")
+	var ran atomic.Int32
+	pool := New(Config{Workers: 1, FailFast: true}, func(_ context.Context, n int) (int, error) {
+		ran.Add(1)
+		if n == 0 {
+			return 0, errFirst
+		}
+		return n, nil
+	})
+	results, err := pool.Run(context.Background(), []int{0, 1, 2, 3, 4, 5, 6, 7})
+	if !errors.Is(err, errFirst) {
+		t.Fatalf("Run error = %v, want errFirst", err)
+	}
+	if n := ran.Load(); n > 2 {
+		t.Errorf("%d tasks ran, want at most 2 with FailFast", n)
+	}
+	if !errors.Is(results[7].Err, errFirst) {
+		t.Errorf("results[7].Err = %v, want the failing task's error", results[7].Err)
+	}
+}
@@ -0,0 +1,63 @@
+package greetings
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NameError records why the name at Index could not be greeted.
+type NameError struct {
+	Index int
+	Name  string
+	Err   error
+}
+
+func (e *NameError) Error() string {
+	return fmt.Sprintf("name %d (%q): %v", e.Index, e.Name, e.Err)
+}
+
+func (e *NameError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError lists every name a batch call failed to greet, in input order.
+type BatchError struct {
+	Errors []*NameError
+}
+
+func (e *BatchError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d names failed: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Unwrap lets errors.Is and errors.As look at the individual failures.
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// GreetsAll greets every valid name and keeps going past invalid ones. It
+// returns the successful greetings together with a *BatchError describing
+// each failure, or a nil error if every name was greeted.
+func (g *Greeter) GreetsAll(names []string) (map[string]string, error) {
+	messages := make(map[string]string)
+	var failed []*NameError
+	for i, name := range names {
+		message, err := g.Greet(name)
+		if err != nil {
+			failed = append(failed, &NameError{Index: i, Name: name, Err: err})
+			continue
+		}
+		messages[name] = message
+	}
+	if len(failed) > 0 {
+		return messages, &BatchError{Errors: failed}
+	}
+	return messages, nil
+}
@@ -0,0 +1,164 @@
+package greetings
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultLocale is the locale used by Greet, Greets and RandomGreet, and the
+// last entry of every fallback chain.
+const DefaultLocale = "en"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// catalog is the content of one locales/<locale>.json file. Greetings can be
+// used at any time of day; the other lists are used by GreetAt.
+type catalog struct {
+	Greetings []string `json:"greetings"`
+	Morning   []string `json:"morning"`
+	Afternoon []string `json:"afternoon"`
+	Evening   []string `json:"evening"`
+	Night     []string `json:"night"`
+}
+
+// templates compiles the entries of c. General greetings are named
+// "<locale>-<n>" and time-of-day ones "<locale>-<time of day>-<n>".
+func (c catalog) templates(locale string) ([]*Template, error) {
+	var out []*Template
+	for _, group := range []struct {
+		timeOfDay string
+		texts     []string
+	}{
+		{"", c.Greetings},
+		{"morning", c.Morning},
+		{"afternoon", c.Afternoon},
+		{"evening", c.Evening},
+		{"night", c.Night},
+	} {
+		prefix := locale
+		if group.timeOfDay != "" {
+			prefix += "-" + group.timeOfDay
+		}
+		for i, text := range group.texts {
+			t, err := Template{
+				Name:      fmt.Sprintf("%s-%d", prefix, i+1),
+				Locale:    locale,
+				TimeOfDay: group.timeOfDay,
+				Text:      text,
+			}.compile()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+var (
+	catalogOnce sync.Once
+	catalogs    map[string][]*Template
+	catalogErr  error
+)
+
+// loadCatalogs parses the embedded locale files once and caches their
+// templates by locale.
+func loadCatalogs() (map[string][]*Template, error) {
+	catalogOnce.Do(func() {
+		entries, err := localeFiles.ReadDir("locales")
+		if err != nil {
+			catalogErr = err
+			return
+		}
+		out := make(map[string][]*Template, len(entries))
+		for _, entry := range entries {
+			name := entry.Name()
+			data, err := localeFiles.ReadFile(path.Join("locales", name))
+			if err != nil {
+				catalogErr = err
+				return
+			}
+			var c catalog
+			if err := json.Unmarshal(data, &c); err != nil {
+				catalogErr = fmt.Errorf("locale %s: %w", name, err)
+				return
+			}
+			if len(c.Greetings) == 0 {
+				catalogErr = fmt.Errorf("locale %s: no greetings", name)
+				return
+			}
+			locale := normalizeLocale(strings.TrimSuffix(name, ".json"))
+			templates, err := c.templates(locale)
+			if err != nil {
+				catalogErr = fmt.Errorf("locale %s: %w", name, err)
+				return
+			}
+			out[locale] = templates
+		}
+		if _, ok := out[DefaultLocale]; !ok {
+			catalogErr = fmt.Errorf("default locale %q is missing", DefaultLocale)
+			return
+		}
+		catalogs = out
+	})
+	return catalogs, catalogErr
+}
+
+// Locales returns the locales available in the embedded catalog, sorted.
+func Locales() []string {
+	c, err := loadCatalogs()
+	if err != nil {
+		return nil
+	}
+	locales := make([]string, 0, len(c))
+	for locale := range c {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// normalizeLocale turns tags like "pt_br" or "PT-br" into "pt-BR": the
+// language is lower-cased, a four letter script is title-cased and any other
+// subtag is upper-cased.
+func normalizeLocale(locale string) string {
+	parts := strings.FieldsFunc(strings.TrimSpace(locale), func(r rune) bool {
+		return r == '-' || r == '_'
+	})
+	for i, part := range parts {
+		switch {
+		case i == 0:
+			parts[i] = strings.ToLower(part)
+		case len(part) == 4:
+			parts[i] = strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+		default:
+			parts[i] = strings.ToUpper(part)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// fallbackChain returns the locales to try for locale, most specific first,
+// e.g. "pt-BR" -> ["pt-BR", "pt", "en"].
+func fallbackChain(locale string) []string {
+	locale = normalizeLocale(locale)
+	var chain []string
+	for locale != "" {
+		chain = append(chain, locale)
+		i := strings.LastIndex(locale, "-")
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	if len(chain) == 0 || chain[len(chain)-1] != DefaultLocale {
+		chain = append(chain, DefaultLocale)
+	}
+	return chain
+}
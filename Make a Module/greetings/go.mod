@@ -1,3 +1,5 @@
 module dev.mfr/greetings
 
 go 1.24.5
+
+require golang.org/x/text v0.24.0
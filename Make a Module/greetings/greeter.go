@@ -0,0 +1,123 @@
+package greetings
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Greeter builds greetings from its own random source and template set. Two
+// Greeters created with the same seed and templates return the same sequence
+// of greetings, which lets tests and replayable demos assert exact output.
+type Greeter struct {
+	mu        sync.Mutex // guards rnd and templates
+	rnd       *rand.Rand
+	templates map[string][]*Template
+	validator *Validator
+	calendar  Calendar
+	history   History
+	avoid     int
+}
+
+// NewGreeter returns a Greeter that draws from src. A nil src uses the
+// global math/rand source.
+func NewGreeter(src rand.Source) *Greeter {
+	g := &Greeter{}
+	if src != nil {
+		g.rnd = rand.New(src)
+	}
+	return g
+}
+
+// NewSeededGreeter returns a Greeter whose choices are fully determined by seed.
+func NewSeededGreeter(seed int64) *Greeter {
+	return NewGreeter(rand.NewSource(seed))
+}
+
+// SetValidator replaces DefaultValidator as the name policy of g. It should
+// be called before g is shared between goroutines.
+func (g *Greeter) SetValidator(v Validator) {
+	g.validator = &v
+}
+
+// defaultGreeter backs the package-level functions.
+var defaultGreeter = NewGreeter(nil)
+
+func (g *Greeter) Greet(name string) (string, error) {
+	return g.GreetIn(name, DefaultLocale)
+}
+
+// GreetIn returns a greeting for name in the given locale. Unknown locales
+// fall back along their chain, e.g. "pt-BR" -> "pt" -> "en".
+func (g *Greeter) GreetIn(name, locale string) (string, error) {
+	return g.GreetWith(TemplateData{Name: name}, locale)
+}
+
+// GreetWith renders a greeting from data in the given locale. The name is
+// cleaned up and checked by the greeter's Validator first, and an empty
+// TimeOfDay is filled in from the current time.
+func (g *Greeter) GreetWith(data TemplateData, locale string) (string, error) {
+	name, err := g.validate(data.Name)
+	if err != nil {
+		return "", err
+	}
+	data.Name = name
+	if data.TimeOfDay == "" {
+		data.TimeOfDay = timeOfDay(time.Now())
+	}
+
+	t, err := g.pickTemplate(data.Name, locale, "")
+	if err != nil {
+		return "", err
+	}
+	return g.render(t, data)
+}
+
+func (g *Greeter) Greets(names []string) (map[string]string, error) {
+	messages := make(map[string]string)
+	for _, name := range names {
+		message, err := g.Greet(name)
+		if err != nil {
+			return nil, err
+		}
+		messages[name] = message
+	}
+	return messages, nil
+}
+
+func (g *Greeter) RandomGreet(name string) string {
+	message, err := g.GreetWith(TemplateData{Name: name}, DefaultLocale)
+	if err != nil {
+		return fmt.Sprintf("Hello, %s!", name)
+	}
+	return message
+}
+
+// render renders t and records the greeting in the greeter's history.
+func (g *Greeter) render(t *Template, data TemplateData) (string, error) {
+	message, err := t.render(data)
+	if err != nil {
+		return "", err
+	}
+	if err := g.remember(data.Name, t); err != nil {
+		return "", err
+	}
+	return message, nil
+}
+
+func (g *Greeter) validate(name string) (string, error) {
+	if g.validator == nil {
+		return DefaultValidator.Validate(name)
+	}
+	return g.validator.Validate(name)
+}
+
+// intn returns a random number in [0, n). *rand.Rand is not safe for
+// concurrent use, so g.mu must be held.
+func (g *Greeter) intn(n int) int {
+	if g.rnd == nil {
+		return rand.Intn(n)
+	}
+	return g.rnd.Intn(n)
+}
@@ -1,38 +1,61 @@
 package greetings
 
+// The package-level functions use a Greeter backed by the global math/rand
+// source. Use NewGreeter or NewSeededGreeter for reproducible output.
+
 import (
-	"errors"
-	"fmt"
-	"math/rand"
+	"context"
+	"io"
+	"time"
 )
 
 func Greet(name string) (string, error) {
-	if name == "" {
-		return "", errors.New("name cannot be empty")
-	}
-
-	return RandomGreet(name), nil
+	return defaultGreeter.Greet(name)
 	// return fmt.Sprintf("Hello, %s!", name), nil
 }
 
+// GreetIn returns a random greeting for name in the given locale. Unknown
+// locales fall back along their chain, e.g. "pt-BR" -> "pt" -> "en".
+func GreetIn(name, locale string) (string, error) {
+	return defaultGreeter.GreetIn(name, locale)
+}
+
 func Greets(names []string) (map[string]string, error) {
-	messages := make(map[string]string)
-	for _, name := range names {
-		message, err := Greet(name)
-		if err != nil {
-			return nil, err
-		}
-		messages[name] = message
-	}
-	return messages, nil
+	return defaultGreeter.Greets(names)
 }
 func RandomGreet(name string) string {
-	format := []string{
-		fmt.Sprintf("Hello, %s!", name),
-		fmt.Sprintf("Greetings, %s!", name),
-		fmt.Sprintf("Salutations, %s!", name),
-		fmt.Sprintf("Welcome, %s!", name),
-		fmt.Sprintf("Hi there, %s!", name),
-	}
-	return format[rand.Intn(len(format))]
+	return defaultGreeter.RandomGreet(name)
+}
+
+// GreetWith renders a greeting from data in the given locale.
+func GreetWith(data TemplateData, locale string) (string, error) {
+	return defaultGreeter.GreetWith(data, locale)
+}
+
+// RegisterTemplate adds t to the templates used by the package-level functions.
+func RegisterTemplate(t Template) error {
+	return defaultGreeter.RegisterTemplate(t)
+}
+
+// LoadTemplates reads a JSON array of templates from r and registers them for
+// the package-level functions.
+func LoadTemplates(r io.Reader) error {
+	return defaultGreeter.LoadTemplates(r)
+}
+
+// GreetsAll greets every valid name and reports the invalid ones in a
+// *BatchError instead of stopping at the first failure.
+func GreetsAll(names []string) (map[string]string, error) {
+	return defaultGreeter.GreetsAll(names)
+}
+
+// GreetsOrdered greets names concurrently and returns the greetings in input
+// order.
+func GreetsOrdered(ctx context.Context, names []string, opts OrderedOptions) ([]Greeting, error) {
+	return defaultGreeter.GreetsOrdered(ctx, names, opts)
+}
+
+// GreetAt greets name according to the time of day on the clock of loc.
+func GreetAt(name string, t time.Time, loc *time.Location) (string, error) {
+	return defaultGreeter.GreetAt(name, t, loc)
 }
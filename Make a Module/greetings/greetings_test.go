@@ -1,8 +1,14 @@
 package greetings
 
 import (
+	"context"
+	"errors"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
+	"unicode"
 )
 
 func TestGreet(t *testing.T) {
@@ -18,6 +24,9 @@ func TestGreetEmpty(t *testing.T) {
 	if msg != "" || err == nil {
 		t.Fatalf(`Greet("") = %q, %v, want "", error`, msg, err)
 	}
+	if !errors.Is(err, ErrEmptyName) {
+		t.Fatalf(`Greet("") error = %v, want ErrEmptyName`, err)
+	}
 }
 
 func TestGreets(t *testing.T) {
@@ -33,3 +42,352 @@ func TestGreets(t *testing.T) {
 		}
 	}
 }
+
+func TestGreetIn(t *testing.T) {
+	name := "Genjirou"
+	want := regexp.MustCompile(`\b` + name + `\b`)
+	for _, locale := range Locales() {
+		msg, err := GreetIn(name, locale)
+		if !want.MatchString(msg) || err != nil {
+			t.Errorf(`GreetIn("Genjirou", %q) = %q, %v, want match for %#q, nil`, locale, msg, err, want)
+		}
+	}
+}
+
+func TestGreetInEmpty(t *testing.T) {
+	msg, err := GreetIn("", "pt-BR")
+	if msg != "" || err == nil {
+		t.Fatalf(`GreetIn("", "pt-BR") = %q, %v, want "", error`, msg, err)
+	}
+}
+
+func TestFallbackChain(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   []string
+	}{
+		{"pt-BR", []string{"pt-BR", "pt", "en"}},
+		{"pt_br", []string{"pt-BR", "pt", "en"}},
+		{"zh-Hant-TW", []string{"zh-Hant-TW", "zh-Hant", "zh", "en"}},
+		{"en", []string{"en"}},
+		{"", []string{"en"}},
+	}
+	for _, tt := range tests {
+		got := fallbackChain(tt.locale)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("fallbackChain(%q) = %q, want %q", tt.locale, got, tt.want)
+		}
+	}
+}
+
+func TestTemplatesForFallback(t *testing.T) {
+	g := NewGreeter(nil)
+	list, err := g.templatesFor("pt-PT", "")
+	if err != nil || list[0].Locale != "pt" {
+		t.Fatalf(`templatesFor("pt-PT", "") = %v, %v, want "pt" templates`, list, err)
+	}
+	list, err = g.templatesFor("xx-YY", "")
+	if err != nil || list[0].Locale != DefaultLocale {
+		t.Fatalf(`templatesFor("xx-YY", "") = %v, %v, want %q templates`, list, err, DefaultLocale)
+	}
+}
+
+// zeroSource always yields 0, so every pick is the first catalog entry.
+type zeroSource struct{}
+
+func (zeroSource) Int63() int64 { return 0 }
+func (zeroSource) Seed(int64)   {}
+
+func TestGreeterExact(t *testing.T) {
+	g := NewGreeter(zeroSource{})
+	msg, err := g.Greet("Genjirou")
+	if want := "Hello, Genjirou!"; msg != want || err != nil {
+		t.Fatalf(`Greet("Genjirou") = %q, %v, want %q, nil`, msg, err, want)
+	}
+	msg, err = g.GreetIn("Genjirou", "pt-BR")
+	if want := "Olá, Genjirou!"; msg != want || err != nil {
+		t.Fatalf(`GreetIn("Genjirou", "pt-BR") = %q, %v, want %q, nil`, msg, err, want)
+	}
+}
+
+func TestSeededGreeterReplay(t *testing.T) {
+	names := []string{"Genjirou", "Hiroshi", "Yuki", "Genjirou", "Hiroshi"}
+	a, b := NewSeededGreeter(42), NewSeededGreeter(42)
+	for _, name := range names {
+		if got, want := a.RandomGreet(name), b.RandomGreet(name); got != want {
+			t.Fatalf("RandomGreet(%q) = %q on replay, want %q", name, got, want)
+		}
+	}
+}
+
+func TestRegisterTemplate(t *testing.T) {
+	g := NewGreeter(zeroSource{})
+	err := g.RegisterTemplate(Template{Name: "en-1", Text: "Good {{.TimeOfDay}}, {{.Title}} {{.Name}}!"})
+	if err != nil {
+		t.Fatalf("RegisterTemplate returned error: %v", err)
+	}
+	msg, err := g.GreetWith(TemplateData{Name: "Genjirou", Title: "Dr.", TimeOfDay: "evening"}, "en-GB")
+	if want := "Good evening, Dr. Genjirou!"; msg != want || err != nil {
+		t.Fatalf("GreetWith = %q, %v, want %q, nil", msg, err, want)
+	}
+}
+
+func TestLoadTemplates(t *testing.T) {
+	g := NewGreeter(zeroSource{})
+	input := `[
+		{"name": "rare", "locale": "id", "text": "Apa kabar, {{.Name}}?", "weight": 1},
+		{"name": "common", "locale": "id", "text": "Selamat {{.TimeOfDay}}, {{.Name}}!", "weight": 3}
+	]`
+	if err := g.LoadTemplates(strings.NewReader(input)); err != nil {
+		t.Fatalf("LoadTemplates returned error: %v", err)
+	}
+	list, err := g.templatesFor("id", "")
+	if err != nil || len(list) != 6 {
+		t.Fatalf(`templatesFor("id", "") = %d templates, %v, want 6, nil`, len(list), err)
+	}
+	if list[5].Name != "common" || list[5].Weight != 3 {
+		t.Errorf("last template = %q weight %d, want \"common\" weight 3", list[5].Name, list[5].Weight)
+	}
+}
+
+func TestLoadTemplatesInvalid(t *testing.T) {
+	tests := []string{
+		`[{"name": "unknown-field", "text": "Hi {{.Nickname}}"}]`,
+		`[{"name": "bad-syntax", "text": "Hi {{.Name"}]`,
+		`[{"name": "negative", "text": "Hi {{.Name}}", "weight": -1}]`,
+		`[{"text": "Hi {{.Name}}"}]`,
+		`[{"name": "ok", "text": "Hi {{.Name}}"}, {"name": "empty", "text": ""}]`,
+		`{"name": "not-an-array"}`,
+	}
+	for _, input := range tests {
+		g := NewGreeter(nil)
+		if err := g.LoadTemplates(strings.NewReader(input)); err == nil {
+			t.Errorf("LoadTemplates(%s) returned nil error", input)
+		}
+		if len(g.templates) != 0 {
+			t.Errorf("LoadTemplates(%s) registered templates despite error", input)
+		}
+	}
+}
+
+func TestGreetsAll(t *testing.T) {
+	names := []string{"Genjirou", "", "Hiroshi", ""}
+	messages, err := GreetsAll(names)
+	if len(messages) != 2 || messages["Genjirou"] == "" || messages["Hiroshi"] == "" {
+		t.Errorf(`GreetsAll(%q) = %v, want greetings for "Genjirou" and "Hiroshi"`, names, messages)
+	}
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf(`GreetsAll(%q) error = %v, want *BatchError`, names, err)
+	}
+	if len(batchErr.Errors) != 2 || batchErr.Errors[0].Index != 1 || batchErr.Errors[1].Index != 3 {
+		t.Errorf(`GreetsAll(%q) failures = %v, want indexes 1 and 3`, names, batchErr.Errors)
+	}
+}
+
+func TestGreetsAllValid(t *testing.T) {
+	names := []string{"Genjirou", "Hiroshi", "Yuki"}
+	messages, err := GreetsAll(names)
+	if len(messages) != 3 || err != nil {
+		t.Fatalf(`GreetsAll(%q) = %v, %v, want 3 greetings, nil`, names, messages, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		v       Validator
+		in      string
+		want    string
+		wantErr error
+	}{
+		{"trim", DefaultValidator, "  Yuki \t", "Yuki", nil},
+		{"whitespace only", DefaultValidator, " \t\n ", "", ErrEmptyName},
+		{"control character", DefaultValidator, "Yu\x1bki", "", ErrInvalidCharacters},
+		{"invalid utf-8", DefaultValidator, "Yu\xffki", "", ErrInvalidCharacters},
+		{"bidi override", DefaultValidator, "Yuki\u202eikuY", "", ErrInvalidCharacters},
+		{"bidi isolate", DefaultValidator, "\u2067Yuki", "", ErrInvalidCharacters},
+		{"zero-width non-joiner", DefaultValidator, "\u0646\u06cc\u06a9\u200c\u0646\u0627\u0645", "\u0646\u06cc\u06a9\u200c\u0646\u0627\u0645", nil},
+		{"zero-width joiner", DefaultValidator, "\u0915\u094d\u200d\u0937", "\u0915\u094d\u200d\u0937", nil},
+		{"too long", DefaultValidator, strings.Repeat("a", 10*1024), "", ErrNameTooLong},
+		{"nfc", DefaultValidator, "Jose\u0301", "Jos\u00e9", nil},
+		{"title case", Validator{Trim: true, TitleCase: true}, " ada lovelace", "Ada Lovelace", nil},
+		{"custom reject", Validator{Reject: func(r rune) bool { return unicode.IsDigit(r) }}, "R2D2", "", ErrInvalidCharacters},
+		{"no limit", Validator{}, strings.Repeat("a", 1000), strings.Repeat("a", 1000), nil},
+	}
+	for _, tt := range tests {
+		got, err := tt.v.Validate(tt.in)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Validate(%q) = %q, %v, want %q, %v", tt.name, tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestGreeterSetValidator(t *testing.T) {
+	g := NewGreeter(zeroSource{})
+	g.SetValidator(Validator{Trim: true, TitleCase: true, MaxLength: 5})
+	msg, err := g.Greet(" yuki ")
+	if want := "Hello, Yuki!"; msg != want || err != nil {
+		t.Fatalf(`Greet(" yuki ") = %q, %v, want %q, nil`, msg, err, want)
+	}
+	if _, err := g.Greet("Genjirou"); !errors.Is(err, ErrNameTooLong) {
+		t.Fatalf(`Greet("Genjirou") error = %v, want ErrNameTooLong`, err)
+	}
+}
+
+func TestGreetsOrdered(t *testing.T) {
+	names := []string{"Genjirou", "Hiroshi", "", "Yuki", "Genjirou"}
+	greetings, err := NewGreeter(zeroSource{}).GreetsOrdered(context.Background(), names, OrderedOptions{Workers: 3})
+	if err != nil || len(greetings) != len(names) {
+		t.Fatalf("GreetsOrdered(%q) = %d greetings, %v, want %d, nil", names, len(greetings), err, len(names))
+	}
+	for i, g := range greetings {
+		if g.Name != names[i] {
+			t.Errorf("greetings[%d].Name = %q, want %q", i, g.Name, names[i])
+		}
+		if names[i] == "" {
+			if !errors.Is(g.Err, ErrEmptyName) {
+				t.Errorf("greetings[%d].Err = %v, want ErrEmptyName", i, g.Err)
+			}
+			continue
+		}
+		if want := "Hello, " + names[i] + "!"; g.Message != want || g.Err != nil {
+			t.Errorf("greetings[%d] = %q, %v, want %q, nil", i, g.Message, g.Err, want)
+		}
+	}
+}
+
+func TestGreetsOrderedCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	names := make([]string, 1000)
+	for i := range names {
+		names[i] = "Yuki"
+	}
+	greetings, err := GreetsOrdered(ctx, names, OrderedOptions{Workers: 2})
+	if !errors.Is(err, context.Canceled) || len(greetings) != len(names) {
+		t.Fatalf("GreetsOrdered with canceled context = %d greetings, %v, want %d, context.Canceled", len(greetings), err, len(names))
+	}
+	if last := greetings[len(greetings)-1]; !errors.Is(last.Err, context.Canceled) {
+		t.Errorf("last greeting Err = %v, want context.Canceled", last.Err)
+	}
+}
+
+func TestGreetAt(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	instant := time.Date(2025, time.March, 3, 1, 30, 0, 0, time.UTC) // 08:30 in Jakarta
+	g := NewGreeter(zeroSource{})
+	tests := []struct {
+		locale string
+		loc    *time.Location
+		want   string
+	}{
+		{"en", jakarta, "Good morning, Yuki!"},
+		{"en", time.UTC, "Hello, Yuki! Burning the midnight oil?"},
+		{"en", nil, "Hello, Yuki! Burning the midnight oil?"},
+		{"id", jakarta, "Selamat pagi, Yuki!"},
+		{"ja-JP", jakarta, "おはようございます、Yukiさん！"},
+	}
+	for _, tt := range tests {
+		msg, err := g.GreetAtIn("Yuki", tt.locale, instant, tt.loc)
+		if msg != tt.want || err != nil {
+			t.Errorf("GreetAtIn(%q, %q, %v) = %q, %v, want %q, nil", "Yuki", tt.locale, tt.loc, msg, err, tt.want)
+		}
+	}
+}
+
+func TestGreetAtFallsBackToGeneralTemplates(t *testing.T) {
+	g := NewGreeter(zeroSource{})
+	if err := g.RegisterTemplate(Template{Name: "hey", Locale: "fr", Text: "Salut, {{.Name}} !"}); err != nil {
+		t.Fatalf("RegisterTemplate returned error: %v", err)
+	}
+	msg, err := g.GreetAtIn("Yuki", "fr", time.Date(2025, time.March, 3, 9, 0, 0, 0, time.UTC), nil)
+	if want := "Salut, Yuki !"; msg != want || err != nil {
+		t.Fatalf("GreetAtIn = %q, %v, want %q, nil", msg, err, want)
+	}
+}
+
+func TestGreetAtOccasion(t *testing.T) {
+	calendar := NewAnnualCalendar()
+	occasions := []Occasion{
+		{Name: "New Year", Text: "Happy {{.Occasion}}, {{.Name}}!"},
+		{Name: "Tahun Baru", Locale: "id", Text: "Selamat Tahun Baru, {{.Name}}!"},
+		{Name: "Hogmanay", Locale: "en", Text: "Happy Hogmanay, {{.Name}}!"},
+	}
+	for _, o := range occasions {
+		if err := calendar.Add(time.January, 1, o); err != nil {
+			t.Fatalf("Add(%q) returned error: %v", o.Name, err)
+		}
+	}
+	if err := calendar.Add(time.February, 32, Occasion{Name: "bad", Text: "Hi"}); err == nil {
+		t.Errorf("Add(February 32) returned nil error")
+	}
+	if err := calendar.Add(time.May, 1, Occasion{Name: "broken", Text: "Hi {{.Nope}}"}); err == nil {
+		t.Errorf("Add with invalid template returned nil error")
+	}
+
+	g := NewGreeter(zeroSource{})
+	g.SetCalendar(calendar)
+	// 23:00 UTC on New Year's Eve is already January 1st in Jakarta.
+	instant := time.Date(2024, time.December, 31, 23, 0, 0, 0, time.UTC)
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	tests := []struct {
+		locale string
+		loc    *time.Location
+		want   string
+	}{
+		{"en-GB", jakarta, "Happy Hogmanay, Yuki!"},
+		{"ja", jakarta, "Happy New Year, Yuki!"},
+		{"id", jakarta, "Selamat Tahun Baru, Yuki!"},
+		{"en", time.UTC, "Hello, Yuki! Burning the midnight oil?"},
+	}
+	for _, tt := range tests {
+		msg, err := g.GreetAtIn("Yuki", tt.locale, instant, tt.loc)
+		if msg != tt.want || err != nil {
+			t.Errorf("GreetAtIn(%q, %q, %v) = %q, %v, want %q, nil", "Yuki", tt.locale, tt.loc, msg, err, tt.want)
+		}
+	}
+}
+
+func TestGreeterHistoryAvoidsRepeats(t *testing.T) {
+	g := NewGreeter(zeroSource{})
+	g.SetHistory(NewMemoryHistory(), 4)
+	want := []string{"Hello", "Greetings", "Salutations", "Welcome", "Hi there", "Hello"}
+	for i, w := range want {
+		msg, err := g.Greet("Yuki")
+		if w += ", Yuki!"; msg != w || err != nil {
+			t.Fatalf("greeting %d = %q, %v, want %q, nil", i, msg, err, w)
+		}
+	}
+	if n, err := g.GreetCount("Yuki"); n != len(want) || err != nil {
+		t.Errorf(`GreetCount("Yuki") = %d, %v, want %d, nil`, n, err, len(want))
+	}
+	if n, err := g.GreetCount("Hiroshi"); n != 0 || err != nil {
+		t.Errorf(`GreetCount("Hiroshi") = %d, %v, want 0, nil`, n, err)
+	}
+}
+
+func TestFileHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	h, err := OpenFileHistory(path)
+	if err != nil {
+		t.Fatalf("OpenFileHistory returned error: %v", err)
+	}
+	for _, template := range []string{"en-1", "en-2", "en-3"} {
+		if err := h.Record("Yuki", template); err != nil {
+			t.Fatalf("Record returned error: %v", err)
+		}
+	}
+
+	reopened, err := OpenFileHistory(path)
+	if err != nil {
+		t.Fatalf("OpenFileHistory after Record returned error: %v", err)
+	}
+	if n, err := reopened.Count("Yuki"); n != 3 || err != nil {
+		t.Errorf(`Count("Yuki") = %d, %v, want 3, nil`, n, err)
+	}
+	recent, err := reopened.Recent("Yuki", 2)
+	if strings.Join(recent, ",") != "en-3,en-2" || err != nil {
+		t.Errorf(`Recent("Yuki", 2) = %q, %v, want ["en-3" "en-2"], nil`, recent, err)
+	}
+}
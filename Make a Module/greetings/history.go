@@ -0,0 +1,213 @@
+package greetings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// History remembers which templates were used to greet each name, so a
+// Greeter can avoid repeating itself. Implementations must be safe for
+// concurrent use.
+type History interface {
+	// Record notes that name was greeted with the template called template.
+	Record(name, template string) error
+	// Recent returns up to n template names last used for name, most recent
+	// first.
+	Recent(name string, n int) ([]string, error)
+	// Count returns how many times name has been greeted.
+	Count(name string) (int, error)
+}
+
+// historyKeep is the number of recent templates kept per name.
+const historyKeep = 32
+
+// historyEntry is what a History knows about one name.
+type historyEntry struct {
+	Count  int      `json:"count"`
+	Recent []string `json:"recent"`
+}
+
+// MemoryHistory is a History that lives as long as the process.
+type MemoryHistory struct {
+	mu      sync.Mutex
+	entries map[string]*historyEntry
+}
+
+func NewMemoryHistory() *MemoryHistory {
+	return &MemoryHistory{entries: make(map[string]*historyEntry)}
+}
+
+func (h *MemoryHistory) Record(name, template string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.record(name, template)
+	return nil
+}
+
+// record updates the entry of name; h.mu must be held.
+func (h *MemoryHistory) record(name, template string) {
+	entry, ok := h.entries[name]
+	if !ok {
+		entry = &historyEntry{}
+		h.entries[name] = entry
+	}
+	entry.Count++
+	entry.Recent = append([]string{template}, entry.Recent...)
+	if len(entry.Recent) > historyKeep {
+		entry.Recent = entry.Recent[:historyKeep]
+	}
+}
+
+func (h *MemoryHistory) Recent(name string, n int) ([]string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entry, ok := h.entries[name]
+	if !ok || n <= 0 {
+		return nil, nil
+	}
+	recent := entry.Recent[:min(n, len(entry.Recent))]
+	return append([]string(nil), recent...), nil
+}
+
+func (h *MemoryHistory) Count(name string) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if entry, ok := h.entries[name]; ok {
+		return entry.Count, nil
+	}
+	return 0, nil
+}
+
+// FileHistory is a History stored as JSON in a file, so it survives across
+// sessions. The file is rewritten after every Record.
+type FileHistory struct {
+	*MemoryHistory
+	path string
+}
+
+// OpenFileHistory loads the history stored at path. A missing file is
+// treated as an empty history and created on the first Record.
+func OpenFileHistory(path string) (*FileHistory, error) {
+	h := &FileHistory{MemoryHistory: NewMemoryHistory(), path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+	if err := json.Unmarshal(data, &h.entries); err != nil {
+		return nil, fmt.Errorf("decoding history %s: %w", path, err)
+	}
+	if h.entries == nil {
+		h.entries = make(map[string]*historyEntry)
+	}
+	return h, nil
+}
+
+func (h *FileHistory) Record(name, template string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.record(name, template)
+	return h.save()
+}
+
+// save writes the history to a temporary file and renames it over the old
+// one, so a crash never leaves a truncated file behind. h.mu must be held.
+func (h *FileHistory) save() error {
+	data, err := json.Marshal(h.entries)
+	if err != nil {
+		return fmt.Errorf("encoding history: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(h.path), filepath.Base(h.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("saving history: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("saving history: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("saving history: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), h.path); err != nil {
+		return fmt.Errorf("saving history: %w", err)
+	}
+	return nil
+}
+
+// SetHistory makes g record every greeting in h and avoid the last avoid
+// templates used for the same name when it has other choices. A nil h
+// disables history. It should be called before g is shared between
+// goroutines.
+func (g *Greeter) SetHistory(h History, avoid int) {
+	g.history = h
+	g.avoid = avoid
+}
+
+// GreetCount returns how many times name has been greeted according to the
+// greeter's history, or 0 if it has none.
+func (g *Greeter) GreetCount(name string) (int, error) {
+	if g.history == nil {
+		return 0, nil
+	}
+	name, err := g.validate(name)
+	if err != nil {
+		return 0, err
+	}
+	return g.history.Count(name)
+}
+
+// recentTemplates returns the templates to avoid for name.
+func (g *Greeter) recentTemplates(name string) ([]string, error) {
+	if g.history == nil || g.avoid <= 0 {
+		return nil, nil
+	}
+	recent, err := g.history.Recent(name, g.avoid)
+	if err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+	return recent, nil
+}
+
+// remember records that name was greeted with t.
+func (g *Greeter) remember(name string, t *Template) error {
+	if g.history == nil {
+		return nil
+	}
+	if err := g.history.Record(name, t.Name); err != nil {
+		return fmt.Errorf("recording history: %w", err)
+	}
+	return nil
+}
+
+// withoutTemplates returns list minus the templates named in names, or list
+// itself if that would leave nothing to choose from.
+func withoutTemplates(list []*Template, names []string) []*Template {
+	if len(names) == 0 {
+		return list
+	}
+	var out []*Template
+	for _, t := range list {
+		avoided := false
+		for _, name := range names {
+			if t.Name == name {
+				avoided = true
+				break
+			}
+		}
+		if !avoided {
+			out = append(out, t)
+		}
+	}
+	if len(out) == 0 {
+		return list
+	}
+	return out
+}
@@ -0,0 +1,142 @@
+package greetings
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Occasion is a special day with its own greeting, such as a holiday.
+type Occasion struct {
+	Name string
+	// Locale limits the occasion to greetings in that locale and its more
+	// specific variants. Empty means every locale.
+	Locale string
+	// Text is the greeting, in the same syntax as Template.Text.
+	Text string
+}
+
+// Calendar reports the occasions falling on a date. Implementations must be
+// safe for concurrent use.
+type Calendar interface {
+	Occasions(date time.Time) []Occasion
+}
+
+// AnnualCalendar is a Calendar of occasions that fall on the same day every
+// year, like New Year's Day.
+type AnnualCalendar struct {
+	mu   sync.RWMutex
+	days map[annualDay][]Occasion
+}
+
+type annualDay struct {
+	month time.Month
+	day   int
+}
+
+func NewAnnualCalendar() *AnnualCalendar {
+	return &AnnualCalendar{days: make(map[annualDay][]Occasion)}
+}
+
+// Add validates o and schedules it on the given day of every year.
+func (c *AnnualCalendar) Add(month time.Month, day int, o Occasion) error {
+	if month < time.January || month > time.December || day < 1 || day > 31 {
+		return fmt.Errorf("occasion %q: invalid date %s %d", o.Name, month, day)
+	}
+	if _, err := o.template(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := annualDay{month, day}
+	c.days[key] = append(c.days[key], o)
+	return nil
+}
+
+func (c *AnnualCalendar) Occasions(date time.Time) []Occasion {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.days[annualDay{date.Month(), date.Day()}]
+}
+
+func (o Occasion) template() (*Template, error) {
+	return Template{Name: o.Name, Locale: o.Locale, Text: o.Text}.compile()
+}
+
+// SetCalendar sets the calendar GreetAt consults for occasions. A nil
+// calendar disables occasion greetings. It should be called before g is
+// shared between goroutines.
+func (g *Greeter) SetCalendar(c Calendar) {
+	g.calendar = c
+}
+
+func (g *Greeter) GreetAt(name string, t time.Time, loc *time.Location) (string, error) {
+	return g.GreetAtIn(name, DefaultLocale, t, loc)
+}
+
+// GreetAtIn greets name as of instant t on the clock of loc, or of t's own
+// location if loc is nil. An occasion from the greeter's calendar for that
+// local date takes precedence; otherwise a "Good morning/afternoon/evening"
+// style template for the local time of day is used.
+func (g *Greeter) GreetAtIn(name, locale string, t time.Time, loc *time.Location) (string, error) {
+	name, err := g.validate(name)
+	if err != nil {
+		return "", err
+	}
+	if loc != nil {
+		t = t.In(loc)
+	}
+	data := TemplateData{Name: name, TimeOfDay: timeOfDay(t)}
+
+	if g.calendar != nil {
+		tmpl, err := g.pickOccasion(g.calendar.Occasions(t), locale)
+		if err != nil {
+			return "", err
+		}
+		if tmpl != nil {
+			data.Occasion = tmpl.Name
+			return g.render(tmpl, data)
+		}
+	}
+
+	tmpl, err := g.pickTemplate(name, locale, data.TimeOfDay)
+	if err != nil {
+		return "", err
+	}
+	return g.render(tmpl, data)
+}
+
+// pickOccasion chooses among the occasions that apply to the most specific
+// locale of the fallback chain of locale. The template of an occasion is
+// named after it. It returns nil if no occasion applies.
+func (g *Greeter) pickOccasion(occasions []Occasion, locale string) (*Template, error) {
+	if len(occasions) == 0 {
+		return nil, nil
+	}
+	// Occasions meant for the default locale do not apply to others, so the
+	// chain ends with the locale-neutral ones instead.
+	chain := fallbackChain(locale)
+	if language, _, _ := strings.Cut(chain[0], "-"); language != DefaultLocale {
+		chain = chain[:len(chain)-1]
+	}
+	for _, candidate := range append(chain, "") {
+		var list []*Template
+		for _, o := range occasions {
+			if normalizeLocale(o.Locale) != candidate {
+				continue
+			}
+			tmpl, err := o.template()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, tmpl)
+		}
+		if len(list) > 0 {
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			return g.pickWeighted(list), nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,80 @@
+package greetings
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// Greeting is the outcome of greeting one name of a batch.
+type Greeting struct {
+	Name    string
+	Message string
+	Err     error
+}
+
+// OrderedOptions configures GreetsOrdered.
+type OrderedOptions struct {
+	// Workers is the number of goroutines greeting names. Zero means
+	// runtime.GOMAXPROCS(0).
+	Workers int
+	// Locale is the locale of the greetings. Empty means DefaultLocale.
+	Locale string
+}
+
+// GreetsOrdered greets names on a bounded pool of workers and returns one
+// Greeting per input, in input order, so duplicate names are kept. Invalid
+// names are reported in their Greeting's Err. If ctx is done before every
+// name is greeted, the remaining entries carry ctx.Err() and so does the
+// returned error.
+//
+// Workers draw from the greeter's random source in no particular order, so
+// the output of a seeded Greeter is only reproducible with one worker.
+func (g *Greeter) GreetsOrdered(ctx context.Context, names []string, opts OrderedOptions) ([]Greeting, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(names))
+	locale := opts.Locale
+	if locale == "" {
+		locale = DefaultLocale
+	}
+
+	results := make([]Greeting, len(names))
+	done := make([]bool, len(names))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				message, err := g.GreetIn(names[i], locale)
+				results[i] = Greeting{Name: names[i], Message: message, Err: err}
+				done[i] = true
+			}
+		}()
+	}
+
+feed:
+	for i := range names {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		for i, ok := range done {
+			if !ok {
+				results[i] = Greeting{Name: names[i], Err: err}
+			}
+		}
+		return results, err
+	}
+	return results, nil
+}
@@ -0,0 +1,245 @@
+package greetings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Template is a greeting format written in text/template syntax and rendered
+// against TemplateData, e.g. "Good {{.TimeOfDay}}, {{.Title}} {{.Name}}!".
+type Template struct {
+	// Name identifies the template within its locale. Registering a template
+	// with the name of an existing one replaces it; the built-in templates
+	// are named "<locale>-<n>", starting at 1.
+	Name string `json:"name"`
+	// Locale is the locale the template belongs to. Empty means DefaultLocale.
+	Locale string `json:"locale"`
+	// TimeOfDay restricts the template to GreetAt calls at that part of the
+	// day: "morning", "afternoon", "evening" or "night". Empty templates are
+	// used by every other call and by GreetAt when no restricted one fits.
+	TimeOfDay string `json:"time_of_day"`
+	Text      string `json:"text"`
+	// Weight is the relative chance of the template being picked among the
+	// templates of its locale. Zero means 1.
+	Weight int `json:"weight"`
+
+	parsed *template.Template
+}
+
+// TemplateData holds the fields available to a Template.
+type TemplateData struct {
+	Name string
+	// TimeOfDay is "morning", "afternoon", "evening" or "night".
+	TimeOfDay string
+	// Title is an optional honorific such as "Dr." or "Ms.".
+	Title string
+	// Occasion is the name of the occasion being celebrated, if any.
+	Occasion string
+}
+
+// sampleData is used to check at load time that a template only refers to
+// fields TemplateData has.
+var sampleData = TemplateData{Name: "Gopher", TimeOfDay: "morning", Title: "Dr.", Occasion: "New Year"}
+
+// compile validates t and returns a normalized copy with its text parsed.
+func (t Template) compile() (*Template, error) {
+	if t.Name == "" {
+		return nil, errors.New("template name cannot be empty")
+	}
+	if strings.TrimSpace(t.Text) == "" {
+		return nil, fmt.Errorf("template %q: text cannot be empty", t.Name)
+	}
+	if t.Weight < 0 {
+		return nil, fmt.Errorf("template %q: weight cannot be negative", t.Name)
+	}
+	if t.Weight == 0 {
+		t.Weight = 1
+	}
+	switch t.TimeOfDay {
+	case "", "morning", "afternoon", "evening", "night":
+	default:
+		return nil, fmt.Errorf("template %q: unknown time of day %q", t.Name, t.TimeOfDay)
+	}
+	t.Locale = normalizeLocale(t.Locale)
+	if t.Locale == "" {
+		t.Locale = DefaultLocale
+	}
+	parsed, err := template.New(t.Name).Option("missingkey=error").Parse(t.Text)
+	if err != nil {
+		return nil, fmt.Errorf("template %q: %w", t.Name, err)
+	}
+	if err := parsed.Execute(io.Discard, sampleData); err != nil {
+		return nil, fmt.Errorf("template %q: %w", t.Name, err)
+	}
+	t.parsed = parsed
+	return &t, nil
+}
+
+func (t *Template) render(data TemplateData) (string, error) {
+	var b strings.Builder
+	if err := t.parsed.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("template %q: %w", t.Name, err)
+	}
+	return b.String(), nil
+}
+
+// timeOfDay names the part of the day t falls in.
+func timeOfDay(t time.Time) string {
+	switch h := t.Hour(); {
+	case h >= 5 && h < 12:
+		return "morning"
+	case h >= 12 && h < 17:
+		return "afternoon"
+	case h >= 17 && h < 22:
+		return "evening"
+	default:
+		return "night"
+	}
+}
+
+// RegisterTemplate validates t and adds it to the greeter, replacing any
+// template of the same name in the same locale.
+func (g *Greeter) RegisterTemplate(t Template) error {
+	compiled, err := t.compile()
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.register(compiled)
+	return nil
+}
+
+// LoadTemplates reads a JSON array of templates from r and registers them.
+// Every template is validated first, so on error none of them are registered.
+func (g *Greeter) LoadTemplates(r io.Reader) error {
+	var templates []Template
+	if err := json.NewDecoder(r).Decode(&templates); err != nil {
+		return fmt.Errorf("decoding templates: %w", err)
+	}
+	compiled := make([]*Template, 0, len(templates))
+	for i, t := range templates {
+		c, err := t.compile()
+		if err != nil {
+			return fmt.Errorf("template %d: %w", i, err)
+		}
+		compiled = append(compiled, c)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, c := range compiled {
+		g.register(c)
+	}
+	return nil
+}
+
+// register stores t; g.mu must be held.
+func (g *Greeter) register(t *Template) {
+	if g.templates == nil {
+		g.templates = make(map[string][]*Template)
+	}
+	list := g.templates[t.Locale]
+	for i, existing := range list {
+		if existing.Name == t.Name {
+			list[i] = t
+			return
+		}
+	}
+	g.templates[t.Locale] = append(list, t)
+}
+
+// templatesFor returns the templates of the first locale in the fallback
+// chain of locale that has any for timeOfDay, merging the built-in catalog
+// with the templates registered on g. Templates restricted to timeOfDay are
+// preferred over unrestricted ones of the same locale; an empty timeOfDay
+// selects unrestricted templates only. g.mu must be held.
+func (g *Greeter) templatesFor(locale, timeOfDay string) ([]*Template, error) {
+	c, err := loadCatalogs()
+	if err != nil {
+		return nil, err
+	}
+	for _, candidate := range fallbackChain(locale) {
+		builtin := c[candidate]
+		registered := g.templates[candidate]
+		merged := make([]*Template, 0, len(builtin)+len(registered))
+		for _, t := range builtin {
+			if override := findTemplate(registered, t.Name); override != nil {
+				t = override
+			}
+			merged = append(merged, t)
+		}
+		for _, t := range registered {
+			if findTemplate(builtin, t.Name) == nil {
+				merged = append(merged, t)
+			}
+		}
+		if timeOfDay != "" {
+			if list := filterTemplates(merged, timeOfDay); len(list) > 0 {
+				return list, nil
+			}
+		}
+		if list := filterTemplates(merged, ""); len(list) > 0 {
+			return list, nil
+		}
+	}
+	return nil, fmt.Errorf("no greetings for locale %q", locale)
+}
+
+// filterTemplates returns the templates of list restricted to timeOfDay.
+func filterTemplates(list []*Template, timeOfDay string) []*Template {
+	var out []*Template
+	for _, t := range list {
+		if t.TimeOfDay == timeOfDay {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func findTemplate(list []*Template, name string) *Template {
+	for _, t := range list {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// pickTemplate chooses a template to greet name with for locale and
+// timeOfDay at random, honoring weights and skipping the templates recently
+// used for name.
+func (g *Greeter) pickTemplate(name, locale, timeOfDay string) (*Template, error) {
+	recent, err := g.recentTemplates(name)
+	if err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	list, err := g.templatesFor(locale, timeOfDay)
+	if err != nil {
+		return nil, err
+	}
+	return g.pickWeighted(withoutTemplates(list, recent)), nil
+}
+
+// pickWeighted chooses one of list at random, honoring weights. g.mu must be
+// held.
+func (g *Greeter) pickWeighted(list []*Template) *Template {
+	total := 0
+	for _, t := range list {
+		total += t.Weight
+	}
+	n := g.intn(total)
+	for _, t := range list {
+		if n < t.Weight {
+			return t
+		}
+		n -= t.Weight
+	}
+	return list[len(list)-1]
+}
@@ -0,0 +1,86 @@
+package greetings
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Errors returned by Validator.Validate, wrapped with details. Check for them
+// with errors.Is.
+var (
+	ErrEmptyName         = errors.New("name cannot be empty")
+	ErrNameTooLong       = errors.New("name is too long")
+	ErrInvalidCharacters = errors.New("name contains invalid characters")
+)
+
+// Validator cleans up a name and checks it before it is greeted.
+type Validator struct {
+	// Trim removes leading and trailing white space.
+	Trim bool
+	// Normalize converts the name to Unicode Normalization Form C, so that
+	// "e" followed by a combining accent and a precomposed "é" compare equal.
+	Normalize bool
+	// MaxLength is the maximum number of characters (runes) after cleanup.
+	// Zero means no limit.
+	MaxLength int
+	// Reject reports whether a character is not allowed in a name. Nil
+	// allows every character.
+	Reject func(r rune) bool
+	// TitleCase upper-cases the first letter of every word, e.g.
+	// "ada lovelace" becomes "Ada Lovelace".
+	TitleCase bool
+}
+
+// DefaultValidator is used by Greeters that have no validator set.
+var DefaultValidator = Validator{
+	Trim:      true,
+	Normalize: true,
+	MaxLength: 256,
+	Reject:    RejectControl,
+}
+
+// RejectControl rejects control characters such as newlines and escape
+// sequences, and the bidi embedding, override and isolate characters that
+// can make a name display as something else. Zero-width joiners and
+// non-joiners are allowed: Persian and Indic names need them.
+func RejectControl(r rune) bool {
+	return unicode.IsControl(r) || ('\u202a' <= r && r <= '\u202e') || ('\u2066' <= r && r <= '\u2069')
+}
+
+// Validate returns the cleaned-up name, or an error wrapping ErrEmptyName,
+// ErrNameTooLong or ErrInvalidCharacters.
+func (v Validator) Validate(name string) (string, error) {
+	if !utf8.ValidString(name) {
+		return "", fmt.Errorf("%w: not valid UTF-8", ErrInvalidCharacters)
+	}
+	if v.Normalize {
+		name = norm.NFC.String(name)
+	}
+	if v.Trim {
+		name = strings.TrimSpace(name)
+	}
+	if name == "" {
+		return "", ErrEmptyName
+	}
+	if v.Reject != nil {
+		for i, r := range name {
+			if v.Reject(r) {
+				return "", fmt.Errorf("%w: %q at byte %d", ErrInvalidCharacters, r, i)
+			}
+		}
+	}
+	if n := utf8.RuneCountInString(name); v.MaxLength > 0 && n > v.MaxLength {
+		return "", fmt.Errorf("%w: %d characters, limit is %d", ErrNameTooLong, n, v.MaxLength)
+	}
+	if v.TitleCase {
+		name = cases.Title(language.Und, cases.NoLower).String(name)
+	}
+	return name, nil
+}
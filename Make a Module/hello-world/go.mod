@@ -5,3 +5,5 @@ go 1.24.5
 replace dev.mfr/greetings => ../greetings
 
 require dev.mfr/greetings v0.0.0-00010101000000-000000000000
+
+require golang.org/x/text v0.24.0 // indirect
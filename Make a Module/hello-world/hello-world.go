@@ -1,30 +1,185 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"strings"
 
 	"dev.mfr/greetings"
 )
 
-func main() {
-	fmt.Println("Hello, World!")
+const usage = `Usage:
+  hello-world greet [flags] NAME...   greet the given names
+  hello-world greet [flags] < names   greet one name per line read from stdin
+  hello-world serve [-addr :8080]     serve GET /greet?name= and POST /greet over HTTP
+  hello-world locales                 list the available locales
+
+Flags for greet (they must come before the names):
+`
 
+func main() {
 	log.SetPrefix("greetings: ")
 	log.SetFlags(0)
-	message, err := greetings.Greet("Genjirou")
-	if err != nil {
-		log.Fatal(err)
+
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the process exit code:
+// 0 on success, 1 on failure (including invalid names in --strict mode) and
+// 2 on usage errors.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		newGreetFlags(stderr).PrintDefaults()
+		return 2
+	}
+
+	switch args[0] {
+	case "greet":
+		return greet(args[1:], stdin, stdout, stderr)
+	case "serve":
+		return serve(args[1:], stderr)
+	case "locales":
+		for _, locale := range greetings.Locales() {
+			fmt.Fprintln(stdout, locale)
+		}
+		return 0
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		newGreetFlags(stdout).PrintDefaults()
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		newGreetFlags(stderr).PrintDefaults()
+		return 2
+	}
+}
+
+// greetFlags holds the options of the greet command.
+type greetFlags struct {
+	*flag.FlagSet
+	locale  string
+	seed    int64
+	format  string
+	file    string
+	strict  bool
+	history string
+}
+
+// historyAvoid is how many of the templates last used for a name --history
+// avoids.
+const historyAvoid = 3
+
+func newGreetFlags(output io.Writer) *greetFlags {
+	f := &greetFlags{FlagSet: flag.NewFlagSet("greet", flag.ContinueOnError)}
+	f.SetOutput(output)
+	f.StringVar(&f.locale, "locale", greetings.DefaultLocale, "locale of the greetings, e.g. pt-BR")
+	f.Int64Var(&f.seed, "seed", 0, "seed for reproducible greetings (0 picks a random one)")
+	f.StringVar(&f.format, "format", "text", "output format: text, json or csv")
+	f.StringVar(&f.file, "file", "", "read names from this file, one per line (- for stdin)")
+	f.BoolVar(&f.strict, "strict", false, "exit with status 1 if any name is invalid")
+	f.StringVar(&f.history, "history", "", "remember greetings in this file to avoid repeating the last few")
+	return f
+}
+
+func greet(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	f := newGreetFlags(stderr)
+	if err := f.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	write, ok := writers[f.format]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown format %q, want text, json or csv\n", f.format)
+		return 2
 	}
-	fmt.Println(message)
 
-	names := []string{"Genjirou", "Hiroshi", "Yuki"}
+	names := f.Args()
+	if f.file != "" || len(names) == 0 {
+		input := stdin
+		if f.file != "" && f.file != "-" {
+			file, err := os.Open(f.file)
+			if err != nil {
+				log.Print(err)
+				return 1
+			}
+			defer file.Close()
+			input = file
+		}
+		lines, err := readNames(input)
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		names = append(names, lines...)
+	}
 
-	messages, err := greetings.Greets(names)
+	greeter := greetings.NewGreeter(nil)
+	opts := greetings.OrderedOptions{Locale: f.locale}
+	if f.seed != 0 {
+		// A single worker keeps the draws from the seeded source in input order.
+		greeter = greetings.NewSeededGreeter(f.seed)
+		opts.Workers = 1
+	}
+	if f.history != "" {
+		history, err := greetings.OpenFileHistory(f.history)
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		greeter.SetHistory(history, historyAvoid)
+	}
+	results, err := greeter.GreetsOrdered(context.Background(), names, opts)
 	if err != nil {
-		log.Fatal(err)
+		log.Print(err)
+		return 1
+	}
+
+	out := bufio.NewWriter(stdout)
+	if err := write(out, results); err != nil {
+		log.Print(err)
+		return 1
+	}
+	if err := out.Flush(); err != nil {
+		log.Print(err)
+		return 1
+	}
+
+	failed := 0
+	for i, result := range results {
+		if result.Err != nil {
+			failed++
+			if f.format == "text" {
+				fmt.Fprintf(stderr, "greetings: name %d (%q): %v\n", i+1, result.Name, result.Err)
+			}
+		}
+	}
+	if f.strict && failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// readNames returns the non-blank lines of r.
+func readNames(r io.Reader) ([]string, error) {
+	var names []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.TrimSpace(line) != "" {
+			names = append(names, line)
+		}
 	}
-	for name, message := range messages {
-		fmt.Printf("%s: %s\n", name, message)
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading names: %w", err)
 	}
+	return names, nil
 }
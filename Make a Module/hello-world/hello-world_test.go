@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runCommand runs the command line args with stdin and returns its exit code
+// and output.
+func runCommand(args []string, stdin string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	code = run(args, strings.NewReader(stdin), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestRunNames(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "names.txt")
+	if err := os.WriteFile(file, []byte("Genjirou\n\nHiroshi\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+		want  []string
+	}{
+		{"args", []string{"greet", "Genjirou", "Hiroshi"}, "", []string{"Genjirou", "Hiroshi"}},
+		{"stdin", []string{"greet"}, "Genjirou\n  \nHiroshi\n", []string{"Genjirou", "Hiroshi"}},
+		{"file", []string{"greet", "--file", file}, "Yuki\n", []string{"Genjirou", "Hiroshi"}},
+		{"file and args", []string{"greet", "--file", file, "Yuki"}, "", []string{"Yuki", "Genjirou", "Hiroshi"}},
+		{"stdin dash", []string{"greet", "--file", "-"}, "Yuki\n", []string{"Yuki"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runCommand(tt.args, tt.stdin)
+			if code != 0 {
+				t.Fatalf("run(%q) = %d, want 0; stderr: %s", tt.args, code, stderr)
+			}
+			lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+			if len(lines) != len(tt.want) {
+				t.Fatalf("run(%q) printed %q, want %d lines", tt.args, stdout, len(tt.want))
+			}
+			for i, name := range tt.want {
+				if !strings.HasPrefix(lines[i], name+": ") || !strings.Contains(strings.TrimPrefix(lines[i], name+": "), name) {
+					t.Errorf("run(%q) line %d = %q, want a greeting for %q", tt.args, i+1, lines[i], name)
+				}
+			}
+		})
+	}
+}
+
+func TestRunFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		check  func(t *testing.T, stdout string)
+	}{
+		{"text", func(t *testing.T, stdout string) {
+			if !strings.HasPrefix(stdout, "Genjirou: ") || strings.Count(stdout, "\n") != 1 {
+				t.Errorf("text output = %q, want one greeting line for Genjirou", stdout)
+			}
+		}},
+		{"json", func(t *testing.T, stdout string) {
+			var records []greetingRecord
+			if err := json.Unmarshal([]byte(stdout), &records); err != nil {
+				t.Fatalf("json output %q: %v", stdout, err)
+			}
+			if len(records) != 2 || records[0].Name != "Genjirou" || records[0].Message == "" || records[0].Error != "" {
+				t.Fatalf("json records = %+v, want a greeting for Genjirou first", records)
+			}
+			if records[1].Name != "" || records[1].Message != "" || records[1].Error == "" {
+				t.Errorf("json record 2 = %+v, want an error for the empty name", records[1])
+			}
+		}},
+		{"csv", func(t *testing.T, stdout string) {
+			records, err := csv.NewReader(strings.NewReader(stdout)).ReadAll()
+			if err != nil {
+				t.Fatalf("csv output %q: %v", stdout, err)
+			}
+			if len(records) != 3 || strings.Join(records[0], ",") != "name,message,error" {
+				t.Fatalf("csv records = %q, want a header and two rows", records)
+			}
+			if records[1][0] != "Genjirou" || records[1][1] == "" || records[1][2] != "" {
+				t.Errorf("csv row 1 = %q, want a greeting for Genjirou", records[1])
+			}
+			if records[2][1] != "" || records[2][2] == "" {
+				t.Errorf("csv row 2 = %q, want an error for the empty name", records[2])
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			code, stdout, stderr := runCommand([]string{"greet", "--format", tt.format, "Genjirou", ""}, "")
+			if code != 0 {
+				t.Fatalf("run with --format %s = %d, want 0; stderr: %s", tt.format, code, stderr)
+			}
+			tt.check(t, stdout)
+		})
+	}
+
+	code, _, stderr := runCommand([]string{"greet", "--format", "xml", "Genjirou"}, "")
+	if code != 2 || !strings.Contains(stderr, `unknown format "xml"`) {
+		t.Errorf("run with --format xml = %d, stderr %q, want 2 and an unknown format message", code, stderr)
+	}
+}
+
+func TestRunSeed(t *testing.T) {
+	args := []string{"greet", "--seed", "42", "Genjirou", "Hiroshi", "Yuki", "Ana", "Bruno"}
+	_, first, _ := runCommand(args, "")
+	for range 5 {
+		code, stdout, stderr := runCommand(args, "")
+		if code != 0 {
+			t.Fatalf("run(%q) = %d, want 0; stderr: %s", args, code, stderr)
+		}
+		if stdout != first {
+			t.Fatalf("run(%q) printed %q, then %q, want the same output", args, first, stdout)
+		}
+	}
+}
+
+func TestRunStrict(t *testing.T) {
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"greet", "Genjirou", ""}, 0},
+		{[]string{"greet", "--strict", "Genjirou", ""}, 1},
+		{[]string{"greet", "--strict", "--format", "json", "Genjirou", ""}, 1},
+		{[]string{"greet", "--strict", "Genjirou"}, 0},
+		{[]string{"greet", "--bogus", "Genjirou"}, 2},
+		{nil, 2},
+	}
+	for _, tt := range tests {
+		if code, _, _ := runCommand(tt.args, ""); code != tt.want {
+			t.Errorf("run(%q) = %d, want %d", tt.args, code, tt.want)
+		}
+	}
+
+	_, _, stderr := runCommand([]string{"greet", "--strict", "Genjirou", ""}, "")
+	if !strings.Contains(stderr, `name 2 ("")`) {
+		t.Errorf("text output stderr = %q, want the invalid name 2 reported", stderr)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"dev.mfr/greetings"
+)
+
+// writers maps each --format value to the function that prints greetings in
+// that format.
+var writers = map[string]func(io.Writer, []greetings.Greeting) error{
+	"text": writeText,
+	"json": writeJSON,
+	"csv":  writeCSV,
+}
+
+// greetingRecord is the JSON form of a greetings.Greeting.
+type greetingRecord struct {
+	Name    string `json:"name"`
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+func newRecord(g greetings.Greeting) greetingRecord {
+	record := greetingRecord{Name: g.Name, Message: g.Message}
+	if g.Err != nil {
+		record.Error = g.Err.Error()
+	}
+	return record
+}
+
+// writeText prints one "name: message" line per successful greeting. Failed
+// names are reported on stderr by the caller.
+func writeText(w io.Writer, results []greetings.Greeting) error {
+	for _, g := range results {
+		if g.Err != nil {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s\n", g.Name, g.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, results []greetings.Greeting) error {
+	records := make([]greetingRecord, len(results))
+	for i, g := range results {
+		records[i] = newRecord(g)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
+
+func writeCSV(w io.Writer, results []greetings.Greeting) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"name", "message", "error"}); err != nil {
+		return err
+	}
+	for _, g := range results {
+		record := newRecord(g)
+		if err := cw.Write([]string{record.Name, record.Message, record.Error}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"dev.mfr/greetings"
+)
+
+// maxBodyBytes bounds the size of a POST /greet request body.
+const maxBodyBytes = 1 << 20
+
+// serve runs the greeting HTTP service until it receives SIGINT or SIGTERM,
+// then waits for in-flight requests to finish.
+func serve(args []string, stderr io.Writer) int {
+	f := flag.NewFlagSet("serve", flag.ContinueOnError)
+	f.SetOutput(stderr)
+	addr := f.String("addr", ":8080", "address to listen on")
+	if err := f.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           logRequests(newHandler(greetings.NewGreeter(nil))),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	errc := make(chan error, 1)
+	go func() {
+		log.Printf("listening on %s", *addr)
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		log.Print(err)
+		return 1
+	case <-ctx.Done():
+	}
+
+	log.Print("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("shutdown: %v", err)
+		return 1
+	}
+	return 0
+}
+
+func newHandler(g *greetings.Greeter) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /greet", func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		locale := r.URL.Query().Get("locale")
+		if locale == "" {
+			locale = greetings.DefaultLocale
+		}
+		message, err := g.GreetIn(name, locale)
+		if err != nil {
+			respondGreetError(w, err)
+			return
+		}
+		respondJSON(w, http.StatusOK, map[string]string{"name": name, "message": message})
+	})
+	mux.HandleFunc("POST /greet", func(w http.ResponseWriter, r *http.Request) {
+		var names []string
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&names); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				respondError(w, http.StatusRequestEntityTooLarge, "body_too_large", fmt.Sprintf("body must be at most %d bytes", tooLarge.Limit))
+				return
+			}
+			respondError(w, http.StatusBadRequest, "invalid_body", "body must be a JSON array of names")
+			return
+		}
+		messages, err := g.Greets(names)
+		if err != nil {
+			respondGreetError(w, err)
+			return
+		}
+		respondJSON(w, http.StatusOK, map[string]any{"greetings": messages})
+	})
+	return mux
+}
+
+// respondGreetError maps the validation errors of the greetings package to a
+// 400 response with a machine-readable code, and anything else to a 500.
+func respondGreetError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, greetings.ErrEmptyName):
+		respondError(w, http.StatusBadRequest, "empty_name", err.Error())
+	case errors.Is(err, greetings.ErrNameTooLong):
+		respondError(w, http.StatusBadRequest, "name_too_long", err.Error())
+	case errors.Is(err, greetings.ErrInvalidCharacters):
+		respondError(w, http.StatusBadRequest, "invalid_characters", err.Error())
+	default:
+		log.Printf("greet: %v", err)
+		respondError(w, http.StatusInternalServerError, "internal", "failed to build greeting")
+	}
+}
+
+func respondError(w http.ResponseWriter, status int, code, message string) {
+	respondJSON(w, status, map[string]string{"error": message, "code": code})
+}
+
+func respondJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("encoding response: %v", err)
+	}
+}
+
+// statusRecorder remembers the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logRequests logs the method, path, status and duration of every request.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		log.Printf("%s %s %d %s", r.Method, r.URL.Path, rec.status, time.Since(start))
+	})
+}
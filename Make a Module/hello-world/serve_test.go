@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"dev.mfr/greetings"
+)
+
+func serveGreet(method, target, body string) *httptest.ResponseRecorder {
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequest(method, target, nil)
+	} else {
+		req = httptest.NewRequest(method, target, strings.NewReader(body))
+	}
+	rec := httptest.NewRecorder()
+	newHandler(greetings.NewSeededGreeter(1)).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGreetGet(t *testing.T) {
+	rec := serveGreet(http.MethodGet, "/greet?name=Genjirou&locale=pt-BR", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /greet = %d, want 200; body: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var got struct{ Name, Message string }
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Genjirou" || !strings.Contains(got.Message, "Genjirou") {
+		t.Errorf("GET /greet = %+v, want a greeting for Genjirou", got)
+	}
+}
+
+func TestGreetPost(t *testing.T) {
+	rec := serveGreet(http.MethodPost, "/greet", `["Genjirou", "Hiroshi"]`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /greet = %d, want 200; body: %s", rec.Code, rec.Body)
+	}
+	var got struct {
+		Greetings map[string]string `json:"greetings"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Genjirou", "Hiroshi"} {
+		if !strings.Contains(got.Greetings[name], name) {
+			t.Errorf("POST /greet greetings[%q] = %q, want a greeting for it", name, got.Greetings[name])
+		}
+	}
+}
+
+func TestGreetErrors(t *testing.T) {
+	long := strings.Repeat("a", greetings.DefaultValidator.MaxLength+1)
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+		code   string
+	}{
+		{"get empty", http.MethodGet, "/greet", "", http.StatusBadRequest, "empty_name"},
+		{"get blank", http.MethodGet, "/greet?name=%20%20", "", http.StatusBadRequest, "empty_name"},
+		{"get too long", http.MethodGet, "/greet?name=" + long, "", http.StatusBadRequest, "name_too_long"},
+		{"get control", http.MethodGet, "/greet?" + url.Values{"name": {"Gen\x1b[31m"}}.Encode(), "", http.StatusBadRequest, "invalid_characters"},
+		{"post empty", http.MethodPost, "/greet", `["Genjirou", ""]`, http.StatusBadRequest, "empty_name"},
+		{"post too long", http.MethodPost, "/greet", `["` + long + `"]`, http.StatusBadRequest, "name_too_long"},
+		{"post control", http.MethodPost, "/greet", `["Gen\u202ejirou"]`, http.StatusBadRequest, "invalid_characters"},
+		{"post not an array", http.MethodPost, "/greet", `{"name": "Genjirou"}`, http.StatusBadRequest, "invalid_body"},
+		{"post too large", http.MethodPost, "/greet", `["` + strings.Repeat("a", maxBodyBytes) + `"]`, http.StatusRequestEntityTooLarge, "body_too_large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveGreet(tt.method, tt.target, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("%s %s = %d, want %d; body: %s", tt.method, tt.target, rec.Code, tt.status, rec.Body)
+			}
+			var got struct{ Error, Code string }
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Code != tt.code || got.Error == "" {
+				t.Errorf("%s %s body = %+v, want code %q and a message", tt.method, tt.target, got, tt.code)
+			}
+		})
+	}
+}
+
+func TestGreetMethodNotAllowed(t *testing.T) {
+	for _, method := range []string{http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		rec := serveGreet(method, "/greet", "")
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s /greet = %d, want 405", method, rec.Code)
+		}
+		if allow := rec.Header().Get("Allow"); !strings.Contains(allow, "GET") || !strings.Contains(allow, "POST") {
+			t.Errorf("%s /greet Allow = %q, want GET and POST", method, allow)
+		}
+	}
+}
@@ -2,9 +2,12 @@ module dev.mfr/main
 
 go 1.24.5
 
+replace dev.mfr/album => ../../album
+
 replace dev.mfr/db => ../db
 
 require (
+	dev.mfr/album v0.0.0-00010101000000-000000000000
 	dev.mfr/db v0.0.0-00010101000000-000000000000
 	github.com/joho/godotenv v1.5.1
 )
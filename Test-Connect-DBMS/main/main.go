@@ -4,17 +4,11 @@ import (
 	"fmt"
 	"os"
 
+	"dev.mfr/album"
 	"dev.mfr/db"
 	"github.com/joho/godotenv"
 )
 
-type Album struct {
-	ID     int
-	Title  string
-	Artist string
-	Price  float32
-}
-
 func main() {
 	fmt.Println("Hello, World!")
 
@@ -55,10 +49,10 @@ func main() {
 
 	GetAlbumByName(database, "Genjirou")
 
-	// newAlbum := Album{		//create is commented out to avoid adding duplicate entries
+	// newAlbum := album.Album{		//create is commented out to avoid adding duplicate entries
 	// 	Title:  "Genjirou's First Album",
 	// 	Artist: "GenjirouHD",
-	// 	Price:  9.99,
+	// 	Price:  999, // cents
 	// }
 
 	// createdAlbum, err := AddAlbum(database, newAlbum)
@@ -84,7 +78,7 @@ func GetDatabaseTable(database *db.DB) {
 }
 
 func GetAlbumData(database *db.DB) {
-	var albums []Album
+	var albums []album.Album
 	rows, err := database.Query("SELECT id, title, artist, price FROM albums")
 	if err != nil {
 		fmt.Println("Error querying albums:", err)
@@ -94,23 +88,23 @@ func GetAlbumData(database *db.DB) {
 
 	fmt.Println("Albums:")
 	for rows.Next() {
-		var album Album
-		if err := rows.Scan(&album.ID, &album.Title, &album.Artist, &album.Price); err != nil {
+		var alb album.Album
+		if err := rows.Scan(&alb.ID, &alb.Title, &alb.Artist, &alb.Price); err != nil {
 			fmt.Println("Error scanning row:", err)
 			continue
 		}
-		albums = append(albums, album)
+		albums = append(albums, alb)
 	}
 	if err := rows.Err(); err != nil {
 		fmt.Println("Error with rows:", err)
 	}
 	for _, alb := range albums {
-		fmt.Printf("ID: %d, Title: %s, Artist: %s, Price: %.2f\n", alb.ID, alb.Title, alb.Artist, alb.Price)
+		fmt.Printf("ID: %d, Title: %s, Artist: %s, Price: %s\n", alb.ID, alb.Title, alb.Artist, alb.Price)
 	}
 }
 
 func GetAlbumsByArtist(database *db.DB, artist string) {
-	var albums []Album
+	var albums []album.Album
 	rows, err := database.Query("SELECT id, title, artist, price FROM albums WHERE artist = ?", artist)
 	if err != nil {
 		fmt.Println("Error querying albums by artist:", err)
@@ -119,7 +113,7 @@ func GetAlbumsByArtist(database *db.DB, artist string) {
 	defer rows.Close()
 
 	for rows.Next() {
-		var alb Album
+		var alb album.Album
 		if err := rows.Scan(&alb.ID, &alb.Title, &alb.Artist, &alb.Price); err != nil {
 			fmt.Printf("Error scanning album by artist %q: %v\n", artist, err)
 			return
@@ -128,7 +122,7 @@ func GetAlbumsByArtist(database *db.DB, artist string) {
 	}
 	fmt.Printf("Albums by %s:\n", artist)
 	for _, alb := range albums {
-		fmt.Printf("ID: %d, Title: %s, Artist: %s, Price: %.2f\n", alb.ID, alb.Title, alb.Artist, alb.Price)
+		fmt.Printf("ID: %d, Title: %s, Artist: %s, Price: %s\n", alb.ID, alb.Title, alb.Artist, alb.Price)
 	}
 	if err := rows.Err(); err != nil {
 		fmt.Println("Error with rows:", err)
@@ -136,36 +130,39 @@ func GetAlbumsByArtist(database *db.DB, artist string) {
 }
 
 func GetAlbumById(database *db.DB, id int) {
-	var album Album
+	var alb album.Album
 	row := database.QueryRow("SELECT id, title, artist, price FROM albums WHERE id = ?", id)
-	if err := row.Scan(&album.ID, &album.Title, &album.Artist, &album.Price); err != nil {
+	if err := row.Scan(&alb.ID, &alb.Title, &alb.Artist, &alb.Price); err != nil {
 		fmt.Printf("Error querying album by ID %d: %v\n", id, err)
 		return
 	}
-	fmt.Printf("Album by ID %d: Title: %s, Artist: %s, Price: %.2f\n", album.ID, album.Title, album.Artist, album.Price)
+	fmt.Printf("Album by ID %d: Title: %s, Artist: %s, Price: %s\n", alb.ID, alb.Title, alb.Artist, alb.Price)
 }
 
 func GetAlbumByName(database *db.DB, name string) {
-	var album Album
+	var alb album.Album
 	row := database.QueryRow("SELECT id, title, artist, price FROM albums WHERE title = ?", name)
-	if err := row.Scan(&album.ID, &album.Title, &album.Artist, &album.Price); err != nil {
+	if err := row.Scan(&alb.ID, &alb.Title, &alb.Artist, &alb.Price); err != nil {
 		fmt.Printf("Error querying album by name %s: %v\n", name, err)
 		return
 	}
-	fmt.Printf("Album by Name %s: ID: %d, Artist: %s, Price: %.2f\n", album.Title, album.ID, album.Artist, album.Price)
+	fmt.Printf("Album by Name %s: ID: %d, Artist: %s, Price: %s\n", alb.Title, alb.ID, alb.Artist, alb.Price)
 }
 
-func AddAlbum(database *db.DB, album Album) (Album, error) {
-	result, err := database.Exec("INSERT INTO albums (title, artist, price) VALUES (?, ?, ?)", album.Title, album.Artist, album.Price)
+func AddAlbum(database *db.DB, alb album.Album) (album.Album, error) {
+	if err := alb.Validate(); err != nil {
+		return album.Album{}, err
+	}
+	result, err := database.Exec("INSERT INTO albums (title, artist, price) VALUES (?, ?, ?)", alb.Title, alb.Artist, alb.Price)
 	if err != nil {
-		return Album{}, fmt.Errorf("error inserting album: %w", err)
+		return album.Album{}, fmt.Errorf("error inserting album: %w", err)
 	}
 
 	id, err := result.LastInsertId()
 	if err != nil {
-		return Album{}, fmt.Errorf("error getting last insert ID: %w", err)
+		return album.Album{}, fmt.Errorf("error getting last insert ID: %w", err)
 	}
 
-	album.ID = int(id)
-	return album, nil
+	alb.ID = id
+	return alb, nil
 }
@@ -2,4 +2,9 @@ module dev.mfr/weather-api
 
 go 1.24.5
 
-require github.com/go-chi/chi/v5 v5.2.2
+require (
+	dev.mfr/go-routine/leakcheck v0.0.0-00010101000000-000000000000
+	github.com/go-chi/chi/v5 v5.2.2
+)
+
+replace dev.mfr/go-routine/leakcheck => ../Go-Routine/leakcheck
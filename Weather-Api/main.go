@@ -68,6 +68,12 @@ type CurrentBlock struct {
 }
 
 func main() {
+	fmt.Println("Starting server on :8080")
+	http.ListenAndServe(":8080", routes())
+}
+
+// routes builds the router serving every endpoint of the API.
+func routes() http.Handler {
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
@@ -77,9 +83,7 @@ func main() {
 	r.Get("/", index)
 	r.Get("/weather", weather)
 	r.Get("/ip", ipAddress)
-
-	fmt.Println("Starting server on :8080")
-	http.ListenAndServe(":8080", r)
+	return r
 }
 
 func index(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dev.mfr/go-routine/leakcheck"
+)
+
+func TestIndex(t *testing.T) {
+	leakcheck.Check(t)
+	srv := httptest.NewServer(routes())
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body["request_id"] == "" || body["client_ip"] != "127.0.0.1" {
+		t.Errorf("GET / = %v, want a request id and client ip 127.0.0.1", body)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"remote addr", nil, "192.0.2.1"},
+		{"forwarded for", map[string]string{"X-Forwarded-For": "203.0.113.7, 10.0.0.1"}, "203.0.113.7"},
+		{"real ip", map[string]string{"X-Real-IP": "198.51.100.2"}, "198.51.100.2"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+		if got := clientIP(req); got != tt.want {
+			t.Errorf("%s: clientIP = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+
+	"dev.mfr/album"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLSTATE codes classify recognizes. Class 08 holds every connection
+// exception.
+const (
+	uniqueViolation      = "23505"
+	checkViolation       = "23514"
+	notNullViolation     = "23502"
+	stringTooLong        = "22001"
+	numericOutOfRange    = "22003"
+	tooManyConnections   = "53300"
+	adminShutdown        = "57P01"
+	crashShutdown        = "57P02"
+	cannotConnectNow     = "57P03"
+	connectionExceptions = "08"
+)
+
+// dbError is a database error classified as one of the album errors.
+// Unwrap returns both, so errors.Is matches the class while errors.As still
+// finds the driver's error.
+type dbError struct {
+	class error
+	err   error
+}
+
+func (e *dbError) Error() string {
+	return e.class.Error() + ": " + e.err.Error()
+}
+
+func (e *dbError) Unwrap() []error {
+	return []error{e.class, e.err}
+}
+
+// classify maps an error of the sqlc queries to album.ErrNotFound,
+// ErrConflict, ErrConstraint or ErrUnavailable, and returns any other error
+// unchanged.
+func classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	var class error
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		class = album.ErrNotFound
+	case errors.As(err, &pgErr):
+		switch code := pgErr.Code; {
+		case code == uniqueViolation:
+			class = album.ErrConflict
+		case code == checkViolation, code == notNullViolation, code == stringTooLong, code == numericOutOfRange:
+			class = album.ErrConstraint
+		case code == tooManyConnections, code == adminShutdown, code == crashShutdown, code == cannotConnectNow,
+			strings.HasPrefix(code, connectionExceptions):
+			class = album.ErrUnavailable
+		}
+	case connectionLost(err):
+		class = album.ErrUnavailable
+	}
+	if class == nil {
+		return err
+	}
+	return &dbError{class: class, err: err}
+}
+
+// connectionLost reports whether err means the database could not be
+// reached, as opposed to the database rejecting a statement.
+func connectionLost(err error) bool {
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	return errors.As(err, &connectErr) ||
+		errors.As(err, &netErr) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		pgconn.Timeout(err)
+}
@@ -8,8 +8,66 @@ package db
 import (
 	"context"
 	"database/sql"
+
+	"dev.mfr/album"
 )
 
+const countAlbums = `-- name: CountAlbums :one
+SELECT COUNT(*) FROM albums
+`
+
+func (q *Queries) CountAlbums(ctx context.Context) (int64, error) {
+	row := q.queryRow(ctx, q.countAlbumsStmt, countAlbums)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countAlbumsByArtist = `-- name: CountAlbumsByArtist :one
+SELECT COUNT(*)
+FROM albums
+WHERE
+    artist ILIKE '%' || $1 || '%'
+`
+
+func (q *Queries) CountAlbumsByArtist(ctx context.Context, artist sql.NullString) (int64, error) {
+	row := q.queryRow(ctx, q.countAlbumsByArtistStmt, countAlbumsByArtist, artist)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countAlbumsByFullTextSearch = `-- name: CountAlbumsByFullTextSearch :one
+SELECT COUNT(*)
+FROM albums
+WHERE
+    to_tsvector(
+        'english',
+        title || ' ' || artist
+    ) @@ plainto_tsquery($1)
+`
+
+func (q *Queries) CountAlbumsByFullTextSearch(ctx context.Context, plaintoTsquery string) (int64, error) {
+	row := q.queryRow(ctx, q.countAlbumsByFullTextSearchStmt, countAlbumsByFullTextSearch, plaintoTsquery)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countAlbumsByTitle = `-- name: CountAlbumsByTitle :one
+SELECT COUNT(*)
+FROM albums
+WHERE
+    title ILIKE '%' || $1 || '%'
+`
+
+func (q *Queries) CountAlbumsByTitle(ctx context.Context, title sql.NullString) (int64, error) {
+	row := q.queryRow(ctx, q.countAlbumsByTitleStmt, countAlbumsByTitle, title)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createAlbum = `-- name: CreateAlbum :one
 INSERT INTO
     albums (title, artist, price)
@@ -18,20 +76,22 @@ RETURNING
     id,
     title,
     artist,
-    price
+    price,
+    version
 `
 
 type CreateAlbumParams struct {
-	Title  string `json:"title"`
-	Artist string `json:"artist"`
-	Price  string `json:"price"`
+	Title  string      `json:"title"`
+	Artist string      `json:"artist"`
+	Price  album.Money `json:"price"`
 }
 
 type CreateAlbumRow struct {
-	ID     int32  `json:"id"`
-	Title  string `json:"title"`
-	Artist string `json:"artist"`
-	Price  string `json:"price"`
+	ID      int32       `json:"id"`
+	Title   string      `json:"title"`
+	Artist  string      `json:"artist"`
+	Price   album.Money `json:"price"`
+	Version int64       `json:"version"`
 }
 
 func (q *Queries) CreateAlbum(ctx context.Context, arg CreateAlbumParams) (CreateAlbumRow, error) {
@@ -42,28 +102,38 @@ func (q *Queries) CreateAlbum(ctx context.Context, arg CreateAlbumParams) (Creat
 		&i.Title,
 		&i.Artist,
 		&i.Price,
+		&i.Version,
 	)
 	return i, err
 }
 
-const deleteAlbum = `-- name: DeleteAlbum :exec
-DELETE FROM albums WHERE id = $1
+const deleteAlbum = `-- name: DeleteAlbum :execrows
+DELETE FROM albums WHERE id = $1 AND (version = $2 OR $2 = 0)
 `
 
-func (q *Queries) DeleteAlbum(ctx context.Context, id int32) error {
-	_, err := q.exec(ctx, q.deleteAlbumStmt, deleteAlbum, id)
-	return err
+type DeleteAlbumParams struct {
+	ID      int32 `json:"id"`
+	Version int64 `json:"version"`
+}
+
+func (q *Queries) DeleteAlbum(ctx context.Context, arg DeleteAlbumParams) (int64, error) {
+	result, err := q.exec(ctx, q.deleteAlbumStmt, deleteAlbum, arg.ID, arg.Version)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const getAlbumByID = `-- name: GetAlbumByID :one
-SELECT id, title, artist, price FROM albums WHERE id = $1
+SELECT id, title, artist, price, version FROM albums WHERE id = $1
 `
 
 type GetAlbumByIDRow struct {
-	ID     int32  `json:"id"`
-	Title  string `json:"title"`
-	Artist string `json:"artist"`
-	Price  string `json:"price"`
+	ID      int32       `json:"id"`
+	Title   string      `json:"title"`
+	Artist  string      `json:"artist"`
+	Price   album.Money `json:"price"`
+	Version int64       `json:"version"`
 }
 
 func (q *Queries) GetAlbumByID(ctx context.Context, id int32) (GetAlbumByIDRow, error) {
@@ -74,6 +144,7 @@ func (q *Queries) GetAlbumByID(ctx context.Context, id int32) (GetAlbumByIDRow,
 		&i.Title,
 		&i.Artist,
 		&i.Price,
+		&i.Version,
 	)
 	return i, err
 }
@@ -96,10 +167,10 @@ type GetAlbumByTitleParams struct {
 }
 
 type GetAlbumByTitleRow struct {
-	ID     int32  `json:"id"`
-	Title  string `json:"title"`
-	Artist string `json:"artist"`
-	Price  string `json:"price"`
+	ID     int32       `json:"id"`
+	Title  string      `json:"title"`
+	Artist string      `json:"artist"`
+	Price  album.Money `json:"price"`
 }
 
 func (q *Queries) GetAlbumByTitle(ctx context.Context, arg GetAlbumByTitleParams) ([]GetAlbumByTitleRow, error) {
@@ -130,6 +201,57 @@ func (q *Queries) GetAlbumByTitle(ctx context.Context, arg GetAlbumByTitleParams
 	return items, nil
 }
 
+const getAlbumByTitleAfter = `-- name: GetAlbumByTitleAfter :many
+SELECT id, title, artist, price
+FROM albums
+WHERE
+    title ILIKE '%' || $3 || '%'
+    AND id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type GetAlbumByTitleAfterParams struct {
+	ID    int32          `json:"id"`
+	Limit int32          `json:"limit"`
+	Title sql.NullString `json:"title"`
+}
+
+type GetAlbumByTitleAfterRow struct {
+	ID     int32       `json:"id"`
+	Title  string      `json:"title"`
+	Artist string      `json:"artist"`
+	Price  album.Money `json:"price"`
+}
+
+func (q *Queries) GetAlbumByTitleAfter(ctx context.Context, arg GetAlbumByTitleAfterParams) ([]GetAlbumByTitleAfterRow, error) {
+	rows, err := q.query(ctx, q.getAlbumByTitleAfterStmt, getAlbumByTitleAfter, arg.ID, arg.Limit, arg.Title)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAlbumByTitleAfterRow
+	for rows.Next() {
+		var i GetAlbumByTitleAfterRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Artist,
+			&i.Price,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getAlbums = `-- name: GetAlbums :many
 SELECT id, title, artist, price
 FROM albums
@@ -145,10 +267,10 @@ type GetAlbumsParams struct {
 }
 
 type GetAlbumsRow struct {
-	ID     int32  `json:"id"`
-	Title  string `json:"title"`
-	Artist string `json:"artist"`
-	Price  string `json:"price"`
+	ID     int32       `json:"id"`
+	Title  string      `json:"title"`
+	Artist string      `json:"artist"`
+	Price  album.Money `json:"price"`
 }
 
 func (q *Queries) GetAlbums(ctx context.Context, arg GetAlbumsParams) ([]GetAlbumsRow, error) {
@@ -179,6 +301,55 @@ func (q *Queries) GetAlbums(ctx context.Context, arg GetAlbumsParams) ([]GetAlbu
 	return items, nil
 }
 
+const getAlbumsAfter = `-- name: GetAlbumsAfter :many
+SELECT id, title, artist, price
+FROM albums
+WHERE
+    id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type GetAlbumsAfterParams struct {
+	ID    int32 `json:"id"`
+	Limit int32 `json:"limit"`
+}
+
+type GetAlbumsAfterRow struct {
+	ID     int32       `json:"id"`
+	Title  string      `json:"title"`
+	Artist string      `json:"artist"`
+	Price  album.Money `json:"price"`
+}
+
+func (q *Queries) GetAlbumsAfter(ctx context.Context, arg GetAlbumsAfterParams) ([]GetAlbumsAfterRow, error) {
+	rows, err := q.query(ctx, q.getAlbumsAfterStmt, getAlbumsAfter, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAlbumsAfterRow
+	for rows.Next() {
+		var i GetAlbumsAfterRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Artist,
+			&i.Price,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getAlbumsByArtist = `-- name: GetAlbumsByArtist :many
 SELECT id, title, artist, price
 FROM albums
@@ -197,10 +368,10 @@ type GetAlbumsByArtistParams struct {
 }
 
 type GetAlbumsByArtistRow struct {
-	ID     int32  `json:"id"`
-	Title  string `json:"title"`
-	Artist string `json:"artist"`
-	Price  string `json:"price"`
+	ID     int32       `json:"id"`
+	Title  string      `json:"title"`
+	Artist string      `json:"artist"`
+	Price  album.Money `json:"price"`
 }
 
 func (q *Queries) GetAlbumsByArtist(ctx context.Context, arg GetAlbumsByArtistParams) ([]GetAlbumsByArtistRow, error) {
@@ -231,6 +402,57 @@ func (q *Queries) GetAlbumsByArtist(ctx context.Context, arg GetAlbumsByArtistPa
 	return items, nil
 }
 
+const getAlbumsByArtistAfter = `-- name: GetAlbumsByArtistAfter :many
+SELECT id, title, artist, price
+FROM albums
+WHERE
+    artist ILIKE '%' || $3 || '%'
+    AND id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type GetAlbumsByArtistAfterParams struct {
+	ID     int32          `json:"id"`
+	Limit  int32          `json:"limit"`
+	Artist sql.NullString `json:"artist"`
+}
+
+type GetAlbumsByArtistAfterRow struct {
+	ID     int32       `json:"id"`
+	Title  string      `json:"title"`
+	Artist string      `json:"artist"`
+	Price  album.Money `json:"price"`
+}
+
+func (q *Queries) GetAlbumsByArtistAfter(ctx context.Context, arg GetAlbumsByArtistAfterParams) ([]GetAlbumsByArtistAfterRow, error) {
+	rows, err := q.query(ctx, q.getAlbumsByArtistAfterStmt, getAlbumsByArtistAfter, arg.ID, arg.Limit, arg.Artist)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAlbumsByArtistAfterRow
+	for rows.Next() {
+		var i GetAlbumsByArtistAfterRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Artist,
+			&i.Price,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getAlbumsByFullTextSearch = `-- name: GetAlbumsByFullTextSearch :many
 SELECT id, title, artist, price
 FROM albums
@@ -252,10 +474,10 @@ type GetAlbumsByFullTextSearchParams struct {
 }
 
 type GetAlbumsByFullTextSearchRow struct {
-	ID     int32  `json:"id"`
-	Title  string `json:"title"`
-	Artist string `json:"artist"`
-	Price  string `json:"price"`
+	ID     int32       `json:"id"`
+	Title  string      `json:"title"`
+	Artist string      `json:"artist"`
+	Price  album.Money `json:"price"`
 }
 
 func (q *Queries) GetAlbumsByFullTextSearch(ctx context.Context, arg GetAlbumsByFullTextSearchParams) ([]GetAlbumsByFullTextSearchRow, error) {
@@ -286,33 +508,145 @@ func (q *Queries) GetAlbumsByFullTextSearch(ctx context.Context, arg GetAlbumsBy
 	return items, nil
 }
 
+const getAlbumsByFullTextSearchAfter = `-- name: GetAlbumsByFullTextSearchAfter :many
+SELECT id, title, artist, price
+FROM albums
+WHERE
+    to_tsvector(
+        'english',
+        title || ' ' || artist
+    ) @@ plainto_tsquery($1)
+    AND id > $2
+ORDER BY id
+LIMIT $3
+`
+
+type GetAlbumsByFullTextSearchAfterParams struct {
+	PlaintoTsquery string `json:"plainto_tsquery"`
+	ID             int32  `json:"id"`
+	Limit          int32  `json:"limit"`
+}
+
+type GetAlbumsByFullTextSearchAfterRow struct {
+	ID     int32       `json:"id"`
+	Title  string      `json:"title"`
+	Artist string      `json:"artist"`
+	Price  album.Money `json:"price"`
+}
+
+func (q *Queries) GetAlbumsByFullTextSearchAfter(ctx context.Context, arg GetAlbumsByFullTextSearchAfterParams) ([]GetAlbumsByFullTextSearchAfterRow, error) {
+	rows, err := q.query(ctx, q.getAlbumsByFullTextSearchAfterStmt, getAlbumsByFullTextSearchAfter, arg.PlaintoTsquery, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAlbumsByFullTextSearchAfterRow
+	for rows.Next() {
+		var i GetAlbumsByFullTextSearchAfterRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Artist,
+			&i.Price,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const patchAlbum = `-- name: PatchAlbum :one
+UPDATE albums
+SET
+    title = COALESCE($1, title),
+    artist = COALESCE($2, artist),
+    price = COALESCE($3, price),
+    version = version + 1
+WHERE
+    id = $4
+    AND (version = $5 OR $5 = 0)
+RETURNING
+    id,
+    title,
+    artist,
+    price,
+    version
+`
+
+type PatchAlbumParams struct {
+	Title   sql.NullString `json:"title"`
+	Artist  sql.NullString `json:"artist"`
+	Price   *album.Money   `json:"price"`
+	ID      int32          `json:"id"`
+	Version int64          `json:"version"`
+}
+
+type PatchAlbumRow struct {
+	ID      int32       `json:"id"`
+	Title   string      `json:"title"`
+	Artist  string      `json:"artist"`
+	Price   album.Money `json:"price"`
+	Version int64       `json:"version"`
+}
+
+func (q *Queries) PatchAlbum(ctx context.Context, arg PatchAlbumParams) (PatchAlbumRow, error) {
+	row := q.queryRow(ctx, q.patchAlbumStmt, patchAlbum,
+		arg.Title,
+		arg.Artist,
+		arg.Price,
+		arg.ID,
+		arg.Version,
+	)
+	var i PatchAlbumRow
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Artist,
+		&i.Price,
+		&i.Version,
+	)
+	return i, err
+}
+
 const updateAlbum = `-- name: UpdateAlbum :one
 UPDATE albums
 SET
     title = $2,
     artist = $3,
-    price = $4
+    price = $4,
+    version = version + 1
 WHERE
     id = $1
+    AND (version = $5 OR $5 = 0)
 RETURNING
     id,
     title,
     artist,
-    price
+    price,
+    version
 `
 
 type UpdateAlbumParams struct {
-	ID     int32  `json:"id"`
-	Title  string `json:"title"`
-	Artist string `json:"artist"`
-	Price  string `json:"price"`
+	ID      int32       `json:"id"`
+	Title   string      `json:"title"`
+	Artist  string      `json:"artist"`
+	Price   album.Money `json:"price"`
+	Version int64       `json:"version"`
 }
 
 type UpdateAlbumRow struct {
-	ID     int32  `json:"id"`
-	Title  string `json:"title"`
-	Artist string `json:"artist"`
-	Price  string `json:"price"`
+	ID      int32       `json:"id"`
+	Title   string      `json:"title"`
+	Artist  string      `json:"artist"`
+	Price   album.Money `json:"price"`
+	Version int64       `json:"version"`
 }
 
 func (q *Queries) UpdateAlbum(ctx context.Context, arg UpdateAlbumParams) (UpdateAlbumRow, error) {
@@ -321,6 +655,7 @@ func (q *Queries) UpdateAlbum(ctx context.Context, arg UpdateAlbumParams) (Updat
 		arg.Title,
 		arg.Artist,
 		arg.Price,
+		arg.Version,
 	)
 	var i UpdateAlbumRow
 	err := row.Scan(
@@ -328,6 +663,7 @@ func (q *Queries) UpdateAlbum(ctx context.Context, arg UpdateAlbumParams) (Updat
 		&i.Title,
 		&i.Artist,
 		&i.Price,
+		&i.Version,
 	)
 	return i, err
 }
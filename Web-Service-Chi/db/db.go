@@ -24,6 +24,18 @@ func New(db DBTX) *Queries {
 func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	q := Queries{db: db}
 	var err error
+	if q.countAlbumsStmt, err = db.PrepareContext(ctx, countAlbums); err != nil {
+		return nil, fmt.Errorf("error preparing query CountAlbums: %w", err)
+	}
+	if q.countAlbumsByArtistStmt, err = db.PrepareContext(ctx, countAlbumsByArtist); err != nil {
+		return nil, fmt.Errorf("error preparing query CountAlbumsByArtist: %w", err)
+	}
+	if q.countAlbumsByFullTextSearchStmt, err = db.PrepareContext(ctx, countAlbumsByFullTextSearch); err != nil {
+		return nil, fmt.Errorf("error preparing query CountAlbumsByFullTextSearch: %w", err)
+	}
+	if q.countAlbumsByTitleStmt, err = db.PrepareContext(ctx, countAlbumsByTitle); err != nil {
+		return nil, fmt.Errorf("error preparing query CountAlbumsByTitle: %w", err)
+	}
 	if q.createAlbumStmt, err = db.PrepareContext(ctx, createAlbum); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateAlbum: %w", err)
 	}
@@ -36,15 +48,30 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.getAlbumByTitleStmt, err = db.PrepareContext(ctx, getAlbumByTitle); err != nil {
 		return nil, fmt.Errorf("error preparing query GetAlbumByTitle: %w", err)
 	}
+	if q.getAlbumByTitleAfterStmt, err = db.PrepareContext(ctx, getAlbumByTitleAfter); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAlbumByTitleAfter: %w", err)
+	}
 	if q.getAlbumsStmt, err = db.PrepareContext(ctx, getAlbums); err != nil {
 		return nil, fmt.Errorf("error preparing query GetAlbums: %w", err)
 	}
+	if q.getAlbumsAfterStmt, err = db.PrepareContext(ctx, getAlbumsAfter); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAlbumsAfter: %w", err)
+	}
 	if q.getAlbumsByArtistStmt, err = db.PrepareContext(ctx, getAlbumsByArtist); err != nil {
 		return nil, fmt.Errorf("error preparing query GetAlbumsByArtist: %w", err)
 	}
+	if q.getAlbumsByArtistAfterStmt, err = db.PrepareContext(ctx, getAlbumsByArtistAfter); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAlbumsByArtistAfter: %w", err)
+	}
 	if q.getAlbumsByFullTextSearchStmt, err = db.PrepareContext(ctx, getAlbumsByFullTextSearch); err != nil {
 		return nil, fmt.Errorf("error preparing query GetAlbumsByFullTextSearch: %w", err)
 	}
+	if q.getAlbumsByFullTextSearchAfterStmt, err = db.PrepareContext(ctx, getAlbumsByFullTextSearchAfter); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAlbumsByFullTextSearchAfter: %w", err)
+	}
+	if q.patchAlbumStmt, err = db.PrepareContext(ctx, patchAlbum); err != nil {
+		return nil, fmt.Errorf("error preparing query PatchAlbum: %w", err)
+	}
 	if q.updateAlbumStmt, err = db.PrepareContext(ctx, updateAlbum); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateAlbum: %w", err)
 	}
@@ -53,6 +80,26 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 
 func (q *Queries) Close() error {
 	var err error
+	if q.countAlbumsStmt != nil {
+		if cerr := q.countAlbumsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countAlbumsStmt: %w", cerr)
+		}
+	}
+	if q.countAlbumsByArtistStmt != nil {
+		if cerr := q.countAlbumsByArtistStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countAlbumsByArtistStmt: %w", cerr)
+		}
+	}
+	if q.countAlbumsByFullTextSearchStmt != nil {
+		if cerr := q.countAlbumsByFullTextSearchStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countAlbumsByFullTextSearchStmt: %w", cerr)
+		}
+	}
+	if q.countAlbumsByTitleStmt != nil {
+		if cerr := q.countAlbumsByTitleStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countAlbumsByTitleStmt: %w", cerr)
+		}
+	}
 	if q.createAlbumStmt != nil {
 		if cerr := q.createAlbumStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createAlbumStmt: %w", cerr)
@@ -73,21 +120,46 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getAlbumByTitleStmt: %w", cerr)
 		}
 	}
+	if q.getAlbumByTitleAfterStmt != nil {
+		if cerr := q.getAlbumByTitleAfterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAlbumByTitleAfterStmt: %w", cerr)
+		}
+	}
 	if q.getAlbumsStmt != nil {
 		if cerr := q.getAlbumsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getAlbumsStmt: %w", cerr)
 		}
 	}
+	if q.getAlbumsAfterStmt != nil {
+		if cerr := q.getAlbumsAfterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAlbumsAfterStmt: %w", cerr)
+		}
+	}
 	if q.getAlbumsByArtistStmt != nil {
 		if cerr := q.getAlbumsByArtistStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getAlbumsByArtistStmt: %w", cerr)
 		}
 	}
+	if q.getAlbumsByArtistAfterStmt != nil {
+		if cerr := q.getAlbumsByArtistAfterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAlbumsByArtistAfterStmt: %w", cerr)
+		}
+	}
 	if q.getAlbumsByFullTextSearchStmt != nil {
 		if cerr := q.getAlbumsByFullTextSearchStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getAlbumsByFullTextSearchStmt: %w", cerr)
 		}
 	}
+	if q.getAlbumsByFullTextSearchAfterStmt != nil {
+		if cerr := q.getAlbumsByFullTextSearchAfterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAlbumsByFullTextSearchAfterStmt: %w", cerr)
+		}
+	}
+	if q.patchAlbumStmt != nil {
+		if cerr := q.patchAlbumStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing patchAlbumStmt: %w", cerr)
+		}
+	}
 	if q.updateAlbumStmt != nil {
 		if cerr := q.updateAlbumStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateAlbumStmt: %w", cerr)
@@ -130,29 +202,47 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                            DBTX
-	tx                            *sql.Tx
-	createAlbumStmt               *sql.Stmt
-	deleteAlbumStmt               *sql.Stmt
-	getAlbumByIDStmt              *sql.Stmt
-	getAlbumByTitleStmt           *sql.Stmt
-	getAlbumsStmt                 *sql.Stmt
-	getAlbumsByArtistStmt         *sql.Stmt
-	getAlbumsByFullTextSearchStmt *sql.Stmt
-	updateAlbumStmt               *sql.Stmt
+	db                                 DBTX
+	tx                                 *sql.Tx
+	countAlbumsStmt                    *sql.Stmt
+	countAlbumsByArtistStmt            *sql.Stmt
+	countAlbumsByFullTextSearchStmt    *sql.Stmt
+	countAlbumsByTitleStmt             *sql.Stmt
+	createAlbumStmt                    *sql.Stmt
+	deleteAlbumStmt                    *sql.Stmt
+	getAlbumByIDStmt                   *sql.Stmt
+	getAlbumByTitleStmt                *sql.Stmt
+	getAlbumByTitleAfterStmt           *sql.Stmt
+	getAlbumsStmt                      *sql.Stmt
+	getAlbumsAfterStmt                 *sql.Stmt
+	getAlbumsByArtistStmt              *sql.Stmt
+	getAlbumsByArtistAfterStmt         *sql.Stmt
+	getAlbumsByFullTextSearchStmt      *sql.Stmt
+	getAlbumsByFullTextSearchAfterStmt *sql.Stmt
+	patchAlbumStmt                     *sql.Stmt
+	updateAlbumStmt                    *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                            tx,
-		tx:                            tx,
-		createAlbumStmt:               q.createAlbumStmt,
-		deleteAlbumStmt:               q.deleteAlbumStmt,
-		getAlbumByIDStmt:              q.getAlbumByIDStmt,
-		getAlbumByTitleStmt:           q.getAlbumByTitleStmt,
-		getAlbumsStmt:                 q.getAlbumsStmt,
-		getAlbumsByArtistStmt:         q.getAlbumsByArtistStmt,
-		getAlbumsByFullTextSearchStmt: q.getAlbumsByFullTextSearchStmt,
-		updateAlbumStmt:               q.updateAlbumStmt,
+		db:                                 tx,
+		tx:                                 tx,
+		countAlbumsStmt:                    q.countAlbumsStmt,
+		countAlbumsByArtistStmt:            q.countAlbumsByArtistStmt,
+		countAlbumsByFullTextSearchStmt:    q.countAlbumsByFullTextSearchStmt,
+		countAlbumsByTitleStmt:             q.countAlbumsByTitleStmt,
+		createAlbumStmt:                    q.createAlbumStmt,
+		deleteAlbumStmt:                    q.deleteAlbumStmt,
+		getAlbumByIDStmt:                   q.getAlbumByIDStmt,
+		getAlbumByTitleStmt:                q.getAlbumByTitleStmt,
+		getAlbumByTitleAfterStmt:           q.getAlbumByTitleAfterStmt,
+		getAlbumsStmt:                      q.getAlbumsStmt,
+		getAlbumsAfterStmt:                 q.getAlbumsAfterStmt,
+		getAlbumsByArtistStmt:              q.getAlbumsByArtistStmt,
+		getAlbumsByArtistAfterStmt:         q.getAlbumsByArtistAfterStmt,
+		getAlbumsByFullTextSearchStmt:      q.getAlbumsByFullTextSearchStmt,
+		getAlbumsByFullTextSearchAfterStmt: q.getAlbumsByFullTextSearchAfterStmt,
+		patchAlbumStmt:                     q.patchAlbumStmt,
+		updateAlbumStmt:                    q.updateAlbumStmt,
 	}
 }
@@ -6,12 +6,15 @@ package db
 
 import (
 	"database/sql"
+
+	"dev.mfr/album"
 )
 
 type Album struct {
 	ID        int32        `json:"id"`
 	Title     string       `json:"title"`
 	Artist    string       `json:"artist"`
-	Price     string       `json:"price"`
+	Price     album.Money  `json:"price"`
 	CreatedAt sql.NullTime `json:"created_at"`
+	Version   int64        `json:"version"`
 }
@@ -4,10 +4,15 @@ go 1.24.5
 
 replace (
 	web-service-chi/db => ./db
+	dev.mfr/album => ../album
+	dev.mfr/go-routine/leakcheck => ../Go-Routine/leakcheck
 )
 
 require (
+	dev.mfr/album v0.0.0-00010101000000-000000000000
+	dev.mfr/go-routine/leakcheck v0.0.0-00010101000000-000000000000
 	github.com/go-chi/chi/v5 v5.2.2
+	github.com/jackc/pgx/v5 v5.7.5
 	github.com/joho/godotenv v1.5.1
 	web-service-chi/db v0.0.0-00010101000000-000000000000
 )
@@ -15,7 +20,6 @@ require (
 require (
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
-	github.com/jackc/pgx/v5 v5.7.5 // indirect
 	github.com/jackc/puddle/v2 v2.2.2 // indirect
 	golang.org/x/crypto v0.37.0 // indirect
 	golang.org/x/sync v0.13.0 // indirect
@@ -1,15 +1,21 @@
 package main
 
 import (
+	"cmp"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
 
-	"dev.mfr/web-service-chi/db"
+	"dev.mfr/album"
+	"dev.mfr/album/bulk"
+	"dev.mfr/album/problem"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -17,9 +23,6 @@ import (
 	"github.com/joho/godotenv"
 )
 
-var queries *db.Queries
-var database *sql.DB
-
 func main() {
 
 	// Load environment variables from .env file
@@ -27,6 +30,32 @@ func main() {
 		fmt.Println("Error loading .env file")
 		return
 	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
+	// ALBUM_STORE selects the backend: postgres (the default) or memory.
+	var albums albumStore
+	switch store := os.Getenv("ALBUM_STORE"); store {
+	case "memory":
+		albums = album.NewMemoryRepository()
+		fmt.Println("Using the in-memory album store")
+	case "", "postgres":
+		database := openDatabase()
+		defer database.Close()
+		migrateUp(database)
+		albums = newPostgresRepository(database)
+	default:
+		log.Fatalf("Unknown ALBUM_STORE %q, want postgres or memory\n", store)
+	}
+
+	http.ListenAndServe(":8080", newRouter(albums))
+
+}
+
+// openDatabase connects to the Postgres database configured in the
+// environment.
+func openDatabase() *sql.DB {
 	databaseUrl := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
 		os.Getenv("DB_USER"),
 		os.Getenv("DB_PASSWORD"),
@@ -36,288 +65,420 @@ func main() {
 		os.Getenv("DB_SSLMODE"),
 	)
 
-	var err error
-	database, err = sql.Open("pgx", databaseUrl)
+	database, err := sql.Open("pgx", databaseUrl)
 	if err != nil {
 		log.Fatalf("Unable to connect to database: %v\n", err)
 	}
-	defer database.Close()
 
 	if err := database.Ping(); err != nil {
 		log.Fatalf("Unable to ping database: %v\n", err)
 	}
 	fmt.Println("Connected to database successfully!")
+	return database
+}
+
+// albumStore is the backend the handlers use.
+type albumStore interface {
+	album.AlbumRepository
+	album.Counter
+	album.BulkCreator
+}
 
-	queries = db.New(database)
-	// Create table if it doesnt exist
-	createTables()
+// server holds the dependencies of the handlers.
+type server struct {
+	albums albumStore
+}
 
-	chi := chi.NewRouter()
-	chi.Use(middleware.Logger)
-	chi.Use(middleware.Recoverer)
+// newRouter builds the router serving every endpoint of the service from
+// albums.
+func newRouter(albums albumStore) http.Handler {
+	s := &server{albums: albums}
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
 
-	chi.Get("/", func(w http.ResponseWriter, r *http.Request) {
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintln(w, "Welcome to the Chi Web Service!")
 	})
-	chi.Get("/albums", getAlbums)
-	chi.Post("/albums", addAlbum)
-	chi.Put("/albums/{id}", updateAlbum)
-	chi.Get("/albums/name/{name}", findAlbumByName)
-	chi.Get("/albums/artist/{artist}", GetAlbumsByArtist)
-	chi.Get("/albums/search", getAlbumsByFullTextSearch)
-	chi.Delete("/albums/{id}", deleteAlbum)
-	chi.Get("/albums/{id}", getAlbumByID)
+	r.Get("/albums", s.getAlbums)
+	r.Post("/albums", s.addAlbum)
+	r.Post("/albums:bulk", s.importAlbums)
+	r.Get("/albums:export", s.exportAlbums)
+	r.Put("/albums/{id}", s.updateAlbum)
+	r.Patch("/albums/{id}", s.patchAlbum)
+	r.Get("/albums/name/{name}", s.findAlbumByName)
+	r.Get("/albums/artist/{artist}", s.GetAlbumsByArtist)
+	r.Get("/albums/search", s.getAlbumsByFullTextSearch)
+	r.Delete("/albums/{id}", s.deleteAlbum)
+	r.Get("/albums/{id}", s.getAlbumByID)
+	return r
+}
 
-	http.ListenAndServe(":8080", chi)
+// writeProblem sends p in response to r.
+func writeProblem(w http.ResponseWriter, r *http.Request, p *problem.Problem) {
+	p.Write(w, r, middleware.GetReqID(r.Context()))
+}
 
+// internalError logs err and sends a 500 that leaves its details out.
+func internalError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	log.Printf("%s: %v [request %s]", msg, err, middleware.GetReqID(r.Context()))
+	writeProblem(w, r, problem.InternalError())
 }
 
-func getAlbums(w http.ResponseWriter, r *http.Request) {
-	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
-	if err != nil || limit <= 0 {
-		limit = 10 // Default limit
-	}
-	page, err := strconv.Atoi(r.URL.Query().Get("page"))
-	if err != nil || page <= 0 {
-		page = 1 // Default page
+// storeError sends the problem for err, returned by the album store: 404,
+// 409, 412, 422 or 503 for the failures the store classifies, or else a 500.
+func storeError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	var p *problem.Problem
+	switch {
+	case errors.Is(err, album.ErrNotFound):
+		p = problem.New(http.StatusNotFound, problem.NotFound, "Album not found")
+	case errors.Is(err, album.ErrConflict):
+		p = problem.New(http.StatusConflict, problem.Conflict, "The album conflicts with an existing one")
+	case errors.Is(err, album.ErrVersionMismatch):
+		p = problem.New(http.StatusPreconditionFailed, problem.PreconditionFailed, "The album was changed by another request")
+	case errors.Is(err, album.ErrConstraint):
+		p = problem.New(http.StatusUnprocessableEntity, problem.ConstraintViolation, "The album breaks a constraint of the database")
+	case errors.Is(err, album.ErrUnavailable):
+		log.Printf("%s: %v [request %s]", msg, err, middleware.GetReqID(r.Context()))
+		p = problem.New(http.StatusServiceUnavailable, problem.Unavailable, "The database is unavailable, try again later")
+	default:
+		internalError(w, r, msg, err)
+		return
 	}
+	writeProblem(w, r, p)
+}
 
-	offset := (page - 1) * limit
+func (s *server) getAlbums(w http.ResponseWriter, r *http.Request) {
+	l, p := parseListing(r)
+	if p != nil {
+		writeProblem(w, r, p)
+		return
+	}
 
-	var albumsRow []db.GetAlbumsRow
-	albumsRow, err = queries.GetAlbums(r.Context(), db.GetAlbumsParams{
-		Limit:  int32(limit),
-		Offset: int32(offset),
-	})
+	Albums, err := s.albums.List(r.Context(), l.query())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error fetching albums: %v", err), http.StatusInternalServerError)
+		storeError(w, r, "Error fetching albums", err)
 		return
 	}
-	var Albums []db.Album
-	for _, row := range albumsRow {
-		Albums = append(Albums, db.Album{
-			ID:     row.ID,
-			Title:  row.Title,
-			Artist: row.Artist,
-			Price:  row.Price,
-		})
+	total, err := l.count(r.Context(), s.albums.Count)
+	if err != nil {
+		storeError(w, r, "Error counting albums", err)
+		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(Albums); err != nil {
-		http.Error(w, fmt.Sprintf("Error encoding albums: %v", err), http.StatusInternalServerError)
+	if err := l.write(w, r, Albums, total); err != nil {
+		log.Printf("Error encoding albums: %v", err)
 		return
 	}
 	fmt.Println("Fetched albums successfully!")
 
 }
-func createTables() {
-	// Create the albums table if it doesn't exist
-	_, err := database.Exec(`
-	CREATE TABLE IF NOT EXISTS albums (
-		id SERIAL PRIMARY KEY,
-		title VARCHAR(255) NOT NULL,
-		artist VARCHAR(255) NOT NULL,
-		price DECIMAL(10, 2) NOT NULL
-	);`)
+func (s *server) addAlbum(w http.ResponseWriter, r *http.Request) {
+	var a album.Album
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		writeProblem(w, r, problem.New(http.StatusBadRequest, problem.InvalidBody, fmt.Sprintf("Error decoding album: %v", err)))
+		return
+	}
+	if err := a.Validate(); err != nil {
+		writeProblem(w, r, problem.Validation(err))
+		return
+	}
+
+	newAlbum, err := s.albums.Create(r.Context(), a)
 	if err != nil {
-		log.Fatalf("Error creating albums table: %v\n", err)
+		storeError(w, r, "Error creating album", err)
+		return
 	}
-	fmt.Println("Albums table created successfully!")
-}
-func addAlbum(w http.ResponseWriter, r *http.Request) {
-	var album db.Album
-	if err := json.NewDecoder(r.Body).Decode(&album); err != nil {
-		http.Error(w, fmt.Sprintf("Error decoding album: %v", err), http.StatusBadRequest)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(newAlbum); err != nil {
+		log.Printf("Error encoding new album: %v", err)
 		return
 	}
+	fmt.Println("Album added successfully!")
+}
 
-	price, err := strconv.ParseFloat(album.Price, 64)
-	if err != nil || album.Title == "" || album.Artist == "" || price <= 0 {
-		http.Error(w, "Invalid album data", http.StatusBadRequest)
+// importAlbums creates the albums of a JSON array, NDJSON or CSV body in one
+// transaction and responds with a report on every row. ?mode=partial creates
+// the valid albums even if other rows are invalid.
+func (s *server) importAlbums(w http.ResponseWriter, r *http.Request) {
+	mode, err := bulk.ParseMode(r.URL.Query().Get("mode"))
+	if err != nil {
+		writeProblem(w, r, problem.New(http.StatusBadRequest, problem.InvalidParameter, err.Error()))
+		return
+	}
+	body := http.MaxBytesReader(w, r.Body, bulk.MaxImportBytes)
+	dec, err := bulk.NewDecoder(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		writeProblem(w, r, problem.New(http.StatusUnsupportedMediaType, problem.UnsupportedMediaType, err.Error()))
 		return
 	}
 
-	newAlbum, err := queries.CreateAlbum(r.Context(), db.CreateAlbumParams{
-		Title:  album.Title,
-		Artist: album.Artist,
-		Price:  album.Price,
-	})
+	report, err := bulk.Import(r.Context(), dec, mode, s.albums)
+	if errors.Is(err, bulk.ErrTooLarge) {
+		writeProblem(w, r, problem.New(http.StatusRequestEntityTooLarge, problem.BodyTooLarge, err.Error()))
+		return
+	}
+	if errors.Is(err, bulk.ErrMalformed) {
+		writeProblem(w, r, problem.New(http.StatusBadRequest, problem.InvalidBody, err.Error()))
+		return
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error creating album: %v", err), http.StatusInternalServerError)
+		storeError(w, r, "Error importing albums", err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(newAlbum); err != nil {
-		http.Error(w, fmt.Sprintf("Error encoding new album: %v", err), http.StatusInternalServerError)
+	w.WriteHeader(report.StatusCode())
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("Error encoding import report: %v", err)
 		return
 	}
-	fmt.Println("Album added successfully!")
+	fmt.Printf("Imported %d of %d albums\n", report.Created, report.Total)
+}
+
+// exportAlbums streams every album as NDJSON or, with ?format=csv, CSV.
+func (s *server) exportAlbums(w http.ResponseWriter, r *http.Request) {
+	format := cmp.Or(r.URL.Query().Get("format"), "ndjson")
+	enc, err := bulk.NewEncoder(format, w)
+	if err != nil {
+		writeProblem(w, r, problem.New(http.StatusBadRequest, problem.InvalidParameter, err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", enc.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=albums.%s", format))
+	n, err := bulk.Export(r.Context(), enc, s.albums.List)
+	if err != nil && n == 0 {
+		w.Header().Del("Content-Disposition")
+		storeError(w, r, "Error exporting albums", err)
+		return
+	}
+	if err != nil {
+		log.Printf("Error exporting albums after %d: %v [request %s]", n, err, middleware.GetReqID(r.Context()))
+		return
+	}
+	fmt.Printf("Exported %d albums\n", n)
 }
-func updateAlbum(w http.ResponseWriter, r *http.Request) {
+
+func (s *server) updateAlbum(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
-		http.Error(w, "Invalid album ID", http.StatusBadRequest)
+		writeProblem(w, r, problem.New(http.StatusBadRequest, problem.InvalidID, "Album ID must be a positive integer"))
 		return
 	}
-	var album db.Album
-	if err := json.NewDecoder(r.Body).Decode(&album); err != nil {
-		http.Error(w, fmt.Sprintf("Error decoding album: %v", err), http.StatusBadRequest)
+	var a album.Album
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		writeProblem(w, r, problem.New(http.StatusBadRequest, problem.InvalidBody, fmt.Sprintf("Error decoding album: %v", err)))
 		return
 	}
-
-	price, err := strconv.ParseFloat(album.Price, 64)
-	if err != nil || album.Title == "" || album.Artist == "" || price <= 0 {
-		http.Error(w, "Invalid album data", http.StatusBadRequest)
+	if err := a.Validate(); err != nil {
+		writeProblem(w, r, problem.Validation(err))
 		return
 	}
 
-	updatedAlbum, err := queries.UpdateAlbum(r.Context(), db.UpdateAlbumParams{
-		ID:     int32(id),
-		Title:  album.Title,
-		Artist: album.Artist,
-		Price:  album.Price,
+	a.ID = int64(id)
+	a.Version, err = album.Precondition(r.Header.Get("If-Match"), func() (album.Album, error) {
+		return s.albums.Get(r.Context(), a.ID)
 	})
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error updating album: %v", err), http.StatusInternalServerError)
+		storeError(w, r, "Error fetching album to check If-Match", err)
+		return
+	}
+	updatedAlbum, err := s.albums.Update(r.Context(), a)
+	if err != nil {
+		storeError(w, r, "Error updating album", err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", updatedAlbum.ETag())
 	if err := json.NewEncoder(w).Encode(updatedAlbum); err != nil {
-		http.Error(w, fmt.Sprintf("Error encoding updated album: %v", err), http.StatusInternalServerError)
+		log.Printf("Error encoding updated album: %v", err)
 		return
 	}
 	fmt.Println("Album updated successfully!")
 }
-func findAlbumByName(w http.ResponseWriter, r *http.Request) {
-	name := chi.URLParam(r, "name")
-	if name == "" {
-		http.Error(w, "Album name is required", http.StatusBadRequest)
+
+// patchAlbum applies a JSON Merge Patch or a JSON Patch, picked by the
+// Content-Type of the request, to an album.
+func (s *server) patchAlbum(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		writeProblem(w, r, problem.New(http.StatusBadRequest, problem.InvalidID, "Album ID must be a positive integer"))
 		return
 	}
-	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
-	if err != nil || limit <= 0 {
-		limit = 10 // Default limit
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, album.MaxPatchBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeProblem(w, r, problem.New(http.StatusRequestEntityTooLarge, problem.BodyTooLarge, fmt.Sprintf("Patch must be at most %d bytes", tooLarge.Limit)))
+		return
 	}
-	page, err := strconv.Atoi(r.URL.Query().Get("page"))
-	if err != nil || page <= 0 {
-		page = 1 // Default page
+	if err != nil {
+		writeProblem(w, r, problem.New(http.StatusBadRequest, problem.InvalidBody, fmt.Sprintf("Error reading patch: %v", err)))
+		return
 	}
-	offset := (page - 1) * limit
-
-	albumsRow, err := queries.GetAlbumByTitle(r.Context(), db.GetAlbumByTitleParams{
-		Title:  sql.NullString{String: name, Valid: true},
-		Limit:  (int32)(limit),
-		Offset: (int32)(offset),
+	patch, version, err := album.ConditionalPatch(r.Header.Get("If-Match"), r.Header.Get("Content-Type"), body, func() (album.Album, error) {
+		return s.albums.Get(r.Context(), int64(id))
 	})
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error fetching album by name: %v", err), http.StatusInternalServerError)
+		if p := problem.Patch(err); p != nil {
+			if p.Status == http.StatusUnsupportedMediaType {
+				w.Header().Set("Accept-Patch", album.AcceptPatch)
+			}
+			writeProblem(w, r, p)
+			return
+		}
+		storeError(w, r, "Error fetching album to patch", err)
+		return
+	}
+	if err := patch.Validate(); err != nil {
+		writeProblem(w, r, problem.Validation(err))
 		return
 	}
 
-	if len(albumsRow) == 0 {
-		http.Error(w, "Album not found", http.StatusNotFound)
+	patchedAlbum, err := s.albums.Patch(r.Context(), int64(id), version, patch)
+	if err != nil {
+		storeError(w, r, "Error patching album", err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(albumsRow); err != nil {
-		http.Error(w, fmt.Sprintf("Error encoding album: %v", err), http.StatusInternalServerError)
+	w.Header().Set("ETag", patchedAlbum.ETag())
+	if err := json.NewEncoder(w).Encode(patchedAlbum); err != nil {
+		log.Printf("Error encoding patched album: %v", err)
+		return
+	}
+	fmt.Println("Album patched successfully!")
+}
+func (s *server) findAlbumByName(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if name == "" {
+		writeProblem(w, r, problem.New(http.StatusBadRequest, problem.MissingParameter, "Album name is required"))
+		return
+	}
+	l, p := parseListing(r)
+	if p != nil {
+		writeProblem(w, r, p)
+		return
+	}
+
+	albums, err := s.albums.SearchByTitle(r.Context(), name, l.query())
+	if err != nil {
+		storeError(w, r, "Error fetching album by name", err)
+		return
+	}
+
+	if len(albums) == 0 {
+		writeProblem(w, r, problem.New(http.StatusNotFound, problem.NotFound, "Album not found"))
+		return
+	}
+
+	total, err := l.count(r.Context(), func(ctx context.Context) (int, error) {
+		return s.albums.CountByTitle(ctx, name)
+	})
+	if err != nil {
+		storeError(w, r, "Error counting albums by name", err)
+		return
+	}
+	if err := l.write(w, r, albums, total); err != nil {
+		log.Printf("Error encoding album: %v", err)
 		return
 	}
 	fmt.Println("Fetched album by name successfully!")
 }
 
-func GetAlbumsByArtist(w http.ResponseWriter, r *http.Request) {
+func (s *server) GetAlbumsByArtist(w http.ResponseWriter, r *http.Request) {
 	artist := chi.URLParam(r, "artist")
 	if artist == "" {
-		http.Error(w, "Artist name is required", http.StatusBadRequest)
+		writeProblem(w, r, problem.New(http.StatusBadRequest, problem.MissingParameter, "Artist name is required"))
 		return
 	}
 
-	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
-	if err != nil || limit <= 0 {
-		limit = 10 // Default limit
+	l, p := parseListing(r)
+	if p != nil {
+		writeProblem(w, r, p)
+		return
 	}
-	page, err := strconv.Atoi(r.URL.Query().Get("page"))
-	if err != nil || page <= 0 {
-		page = 1 // Default page
+
+	albums, err := s.albums.SearchByArtist(r.Context(), artist, l.query())
+	if err != nil {
+		storeError(w, r, "Error querying albums by artist", err)
+		return
 	}
-	offset := (page - 1) * limit
 
-	AlbumRows, err := queries.GetAlbumsByArtist(r.Context(), db.GetAlbumsByArtistParams{
-		Artist: sql.NullString{String: artist, Valid: true},
-		Limit:  int32(limit),
-		Offset: int32(offset),
+	total, err := l.count(r.Context(), func(ctx context.Context) (int, error) {
+		return s.albums.CountByArtist(ctx, artist)
 	})
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error querying albums by artist: %v", err), http.StatusInternalServerError)
+		storeError(w, r, "Error counting albums by artist", err)
 		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(AlbumRows); err != nil {
-		http.Error(w, fmt.Sprintf("Error encoding albums by artist: %v", err), http.StatusInternalServerError)
+	if err := l.write(w, r, albums, total); err != nil {
+		log.Printf("Error encoding albums by artist: %v", err)
 		return
 	}
 	fmt.Printf("Fetched albums by %s successfully!\n", artist)
 }
-func getAlbumsByFullTextSearch(w http.ResponseWriter, r *http.Request) {
+func (s *server) getAlbumsByFullTextSearch(w http.ResponseWriter, r *http.Request) {
 	searchTerm := r.URL.Query().Get("search")
 	if searchTerm == "" {
-		http.Error(w, "Search term is required", http.StatusBadRequest)
+		writeProblem(w, r, problem.New(http.StatusBadRequest, problem.MissingParameter, "Search term is required"))
 		return
 	}
 
-	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
-	if err != nil || limit <= 0 {
-		limit = 10 // Default limit
+	l, p := parseListing(r)
+	if p != nil {
+		writeProblem(w, r, p)
+		return
 	}
-	page, err := strconv.Atoi(r.URL.Query().Get("page"))
-	if err != nil || page <= 0 {
-		page = 1 // Default page
+
+	albums, err := s.albums.FullText(r.Context(), searchTerm, l.query())
+	if err != nil {
+		storeError(w, r, "Error fetching albums by full text search", err)
+		return
 	}
-	offset := (page - 1) * limit
 
-	albumsRow, err := queries.GetAlbumsByFullTextSearch(r.Context(), db.GetAlbumsByFullTextSearchParams{
-		PlaintoTsquery: searchTerm,
-		Limit:          int32(limit),
-		Offset:         int32(offset),
+	total, err := l.count(r.Context(), func(ctx context.Context) (int, error) {
+		return s.albums.CountFullText(ctx, searchTerm)
 	})
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error fetching albums by full text search: %v", err), http.StatusInternalServerError)
+		storeError(w, r, "Error counting albums by full text search", err)
 		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(albumsRow); err != nil {
-		http.Error(w, fmt.Sprintf("Error encoding albums by full text search: %v", err), http.StatusInternalServerError)
+	if err := l.write(w, r, albums, total); err != nil {
+		log.Printf("Error encoding albums by full text search: %v", err)
 		return
 	}
 	fmt.Printf("Fetched albums by full text search '%s' successfully!\n", searchTerm)
 }
 
-func deleteAlbum(w http.ResponseWriter, r *http.Request) {
+func (s *server) deleteAlbum(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		http.Error(w, "Album ID is required", http.StatusBadRequest)
+		writeProblem(w, r, problem.New(http.StatusBadRequest, problem.MissingParameter, "Album ID is required"))
 		return
 	}
 	idInt, err := strconv.Atoi(id)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Invalid album ID: %v", err), http.StatusBadRequest)
+	if err != nil || idInt <= 0 {
+		writeProblem(w, r, problem.New(http.StatusBadRequest, problem.InvalidID, "Album ID must be a positive integer"))
 		return
 	}
-	deletedAlbum, err := queries.GetAlbumByID(r.Context(), int32(idInt))
+	deletedAlbum, err := s.albums.Get(r.Context(), int64(idInt))
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error fetching deleted album: %v", err), http.StatusInternalServerError)
+		storeError(w, r, "Error fetching deleted album", err)
 		return
 	}
 
-	if err := queries.DeleteAlbum(r.Context(), (int32)(idInt)); err != nil {
-		http.Error(w, fmt.Sprintf("Error deleting album: %v", err), http.StatusInternalServerError)
+	version, err := album.Precondition(r.Header.Get("If-Match"), func() (album.Album, error) { return deletedAlbum, nil })
+	if err != nil {
+		storeError(w, r, "Error checking If-Match", err)
+		return
+	}
+	if err := s.albums.Delete(r.Context(), int64(idInt), version); err != nil {
+		storeError(w, r, "Error deleting album", err)
 		return
 	}
 
@@ -331,23 +492,28 @@ func deleteAlbum(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("Album deleted successfully!")
 }
 
-func getAlbumByID(w http.ResponseWriter, r *http.Request) {
+func (s *server) getAlbumByID(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
-		http.Error(w, "Invalid album ID", http.StatusBadRequest)
+		writeProblem(w, r, problem.New(http.StatusBadRequest, problem.InvalidID, "Album ID must be a positive integer"))
 		return
 	}
 
-	album, err := queries.GetAlbumByID(r.Context(), int32(id))
+	a, err := s.albums.Get(r.Context(), int64(id))
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error fetching album by ID: %v", err), http.StatusInternalServerError)
+		storeError(w, r, "Error fetching album by ID", err)
 		return
 	}
 
+	w.Header().Set("ETag", a.ETag())
+	if !album.IfNoneMatch(r.Header.Get("If-None-Match"), a) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(album); err != nil {
-		http.Error(w, fmt.Sprintf("Error encoding album: %v", err), http.StatusInternalServerError)
+	if err := json.NewEncoder(w).Encode(a); err != nil {
+		log.Printf("Error encoding album: %v", err)
 		return
 	}
 	fmt.Printf("Fetched album by ID %d successfully!\n", id)
@@ -0,0 +1,643 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dev.mfr/album"
+	"dev.mfr/album/bulk"
+	"dev.mfr/album/problem"
+	"dev.mfr/go-routine/leakcheck"
+)
+
+// newTestServer serves newRouter(albums) for the duration of t and fails t
+// if any goroutine started while serving outlives it.
+func newTestServer(t *testing.T, albums albumStore) *httptest.Server {
+	t.Helper()
+	leakcheck.Check(t)
+	srv := httptest.NewServer(newRouter(albums))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// seed returns a store holding n albums with IDs 1 to n.
+func seed(n int) *album.MemoryRepository {
+	albums := make([]album.Album, n)
+	for i := range albums {
+		albums[i] = album.Album{
+			ID:     int64(i + 1),
+			Title:  fmt.Sprintf("Album %d", i+1),
+			Artist: []string{"John Coltrane", "Miles Davis"}[i%2],
+			Price:  album.Money(999 + i*100),
+		}
+	}
+	return album.NewMemoryRepository(albums...)
+}
+
+// do sends a request and returns the status and body of the response.
+func do(t *testing.T, srv *httptest.Server, method, path, body string) (int, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, data
+}
+
+// ids decodes a list response into the IDs of its albums.
+func ids(t *testing.T, data []byte) []int64 {
+	t.Helper()
+	var page struct {
+		Data []album.Album `json:"data"`
+	}
+	if err := json.Unmarshal(data, &page); err != nil {
+		t.Fatalf("decoding %s: %v", data, err)
+	}
+	out := make([]int64, len(page.Data))
+	for i, a := range page.Data {
+		out[i] = a.ID
+	}
+	return out
+}
+
+// failingRepository fails every call it does not override, like a database
+// that went away.
+type failingRepository struct {
+	albumStore
+}
+
+var errConnection = errors.New("connection refused")
+
+func (failingRepository) Get(context.Context, int64) (album.Album, error) {
+	return album.Album{}, errConnection
+}
+
+// classifiedRepository fails every call a handler makes with err, one of
+// the errors a store classifies.
+type classifiedRepository struct {
+	albumStore
+	err error
+}
+
+func (c classifiedRepository) Get(context.Context, int64) (album.Album, error) {
+	return album.Album{}, fmt.Errorf("album 1: %w", c.err)
+}
+
+func (c classifiedRepository) List(context.Context, album.Page) ([]album.Album, error) {
+	return nil, c.err
+}
+
+func (c classifiedRepository) Create(context.Context, album.Album) (album.Album, error) {
+	return album.Album{}, c.err
+}
+
+func (c classifiedRepository) Update(context.Context, album.Album) (album.Album, error) {
+	return album.Album{}, c.err
+}
+
+func TestIndex(t *testing.T) {
+	srv := newTestServer(t, seed(0))
+	status, body := do(t, srv, http.MethodGet, "/", "")
+	if status != http.StatusOK || !strings.Contains(string(body), "Welcome") {
+		t.Errorf("GET / = %d %q, want 200 with a welcome message", status, body)
+	}
+}
+
+func TestGetAlbumsPagination(t *testing.T) {
+	srv := newTestServer(t, seed(25))
+	tests := []struct {
+		query       string
+		first, last int64
+		count       int
+	}{
+		{"", 1, 10, 10},
+		{"?limit=5&page=3", 11, 15, 5},
+		{"?limit=10&page=3", 21, 25, 5},
+		{"?page=0", 1, 10, 10},
+		{"?page=2", 11, 20, 10},
+		{"?limit=100", 1, 25, 25},
+	}
+	for _, tt := range tests {
+		status, body := do(t, srv, http.MethodGet, "/albums"+tt.query, "")
+		got := ids(t, body)
+		if status != http.StatusOK || len(got) != tt.count || got[0] != tt.first || got[len(got)-1] != tt.last {
+			t.Errorf("GET /albums%s = %d %v, want %d albums from %d to %d", tt.query, status, got, tt.count, tt.first, tt.last)
+		}
+	}
+	if _, body := do(t, srv, http.MethodGet, "/albums?page=4", ""); len(ids(t, body)) != 0 {
+		t.Errorf("GET /albums?page=4 = %s, want no albums", body)
+	}
+}
+
+func TestListEnvelope(t *testing.T) {
+	srv := newTestServer(t, seed(25))
+	tests := []struct {
+		path       string
+		pagination string
+		link       string
+	}{
+		{
+			"/albums?limit=5&page=3",
+			`{"page":3,"limit":5,"total":25,"total_pages":5,"has_next":true,"has_prev":true}`,
+			`</albums?limit=5&page=1>; rel="first", </albums?limit=5&page=2>; rel="prev", </albums?limit=5&page=4>; rel="next", </albums?limit=5&page=5>; rel="last"`,
+		},
+		{
+			"/albums/artist/miles",
+			`{"page":1,"limit":10,"total":12,"total_pages":2,"has_next":true,"has_prev":false}`,
+			`</albums/artist/miles?limit=10&page=1>; rel="first", </albums/artist/miles?limit=10&page=2>; rel="next", </albums/artist/miles?limit=10&page=2>; rel="last"`,
+		},
+		{
+			"/albums/search?search=nothing",
+			`{"page":1,"limit":10,"total":0,"total_pages":0,"has_next":false,"has_prev":false}`,
+			`</albums/search?limit=10&page=1&search=nothing>; rel="first"`,
+		},
+		{
+			"/albums?limit=20&after=" + album.Cursor{ID: 5}.String(),
+			`{"limit":20,"has_next":false}`,
+			`</albums?after=&limit=20>; rel="first"`,
+		},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(srv.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var page struct {
+			Data       []album.Album   `json:"data"`
+			Pagination json.RawMessage `json:"pagination"`
+		}
+		json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if string(page.Pagination) != tt.pagination {
+			t.Errorf("GET %s pagination = %s, want %s", tt.path, page.Pagination, tt.pagination)
+		}
+		if link := resp.Header.Get("Link"); link != tt.link {
+			t.Errorf("GET %s Link = %s, want %s", tt.path, link, tt.link)
+		}
+	}
+}
+
+func TestGetAlbumsCursor(t *testing.T) {
+	albums := seed(25)
+	srv := newTestServer(t, albums)
+
+	var got []int64
+	path := "/albums?limit=10&after="
+	for range 5 {
+		status, body := do(t, srv, http.MethodGet, path, "")
+		var page struct {
+			Data       []album.Album `json:"data"`
+			Pagination struct {
+				NextCursor string `json:"next_cursor"`
+			} `json:"pagination"`
+		}
+		if err := json.Unmarshal(body, &page); status != http.StatusOK || err != nil {
+			t.Fatalf("GET %s = %d %s, want 200 with a cursor page", path, status, body)
+		}
+		for _, a := range page.Data {
+			got = append(got, a.ID)
+		}
+		if page.Pagination.NextCursor == "" {
+			break
+		}
+		// Removing an album already listed must not shift the next page.
+		albums.Delete(context.Background(), got[0], 0)
+		path = "/albums?limit=10&after=" + page.Pagination.NextCursor
+	}
+	if len(got) != 25 || got[0] != 1 || got[24] != 25 {
+		t.Errorf("walking /albums by cursor listed %v, want every ID from 1 to 25 once", got)
+	}
+
+	status, body := do(t, srv, http.MethodGet, "/albums/artist/miles?limit=1&after="+album.Cursor{ID: 20}.String(), "")
+	if !strings.Contains(string(body), `"id":22`) || !strings.Contains(string(body), `"next_cursor"`) {
+		t.Errorf("GET /albums/artist/miles after 20 = %d %s, want album 22 and a next cursor", status, body)
+	}
+	for _, path := range []string{"/albums?after=abc", "/albums/search?search=john&after=" + album.Cursor{}.String()} {
+		if status, _ := do(t, srv, http.MethodGet, path, ""); status != http.StatusBadRequest {
+			t.Errorf("GET %s = %d, want 400", path, status)
+		}
+	}
+}
+
+func TestAddAlbum(t *testing.T) {
+	albums := seed(0)
+	srv := newTestServer(t, albums)
+
+	status, body := do(t, srv, http.MethodPost, "/albums", `{"title":"Blue Train","artist":"John Coltrane","price":56.99}`)
+	var created album.Album
+	json.Unmarshal(body, &created)
+	if status != http.StatusOK || created.ID != 1 || created.Price != 5699 {
+		t.Fatalf("POST /albums = %d %s, want 200 with ID 1 and price 56.99", status, body)
+	}
+
+	invalid := map[string]string{
+		"malformed JSON":  `{"title":`,
+		"empty title":     `{"title":" ","artist":"x","price":1}`,
+		"missing artist":  `{"title":"x","price":1}`,
+		"zero price":      `{"title":"x","artist":"y","price":0}`,
+		"negative price":  `{"title":"x","artist":"y","price":"-1.00"}`,
+		"sub-cent price":  `{"title":"x","artist":"y","price":1.005}`,
+		"price too large": `{"title":"x","artist":"y","price":100000000}`,
+		"title too long":  fmt.Sprintf(`{"title":%q,"artist":"y","price":1}`, strings.Repeat("t", album.MaxTitleLength+1)),
+	}
+	for name, body := range invalid {
+		if status, resp := do(t, srv, http.MethodPost, "/albums", body); status != http.StatusBadRequest {
+			t.Errorf("POST /albums with %s = %d %q, want 400", name, status, resp)
+		}
+	}
+	if n, _ := albums.Count(context.Background()); n != 1 {
+		t.Errorf("store holds %d albums after invalid requests, want 1", n)
+	}
+}
+
+func TestUpdateAlbum(t *testing.T) {
+	albums := seed(3)
+	srv := newTestServer(t, albums)
+
+	status, body := do(t, srv, http.MethodPut, "/albums/2", `{"title":"Kind of Blue","artist":"Miles Davis","price":"29.99"}`)
+	if status != http.StatusOK {
+		t.Fatalf("PUT /albums/2 = %d %s, want 200", status, body)
+	}
+	stored, err := albums.Get(context.Background(), 2)
+	if err != nil || stored.Title != "Kind of Blue" || stored.Price != 2999 {
+		t.Errorf("album 2 after PUT = %+v, %v, want the new title and price 29.99", stored, err)
+	}
+
+	if status, _ := do(t, srv, http.MethodPut, "/albums/42", `{"title":"x","artist":"y","price":1}`); status != http.StatusNotFound {
+		t.Errorf("PUT /albums/42 = %d, want 404", status)
+	}
+
+	for _, tt := range []struct{ name, path, body string }{
+		{"invalid ID", "/albums/abc", `{"title":"x","artist":"y","price":1}`},
+		{"zero ID", "/albums/0", `{"title":"x","artist":"y","price":1}`},
+		{"malformed JSON", "/albums/1", `not json`},
+		{"missing title", "/albums/1", `{"artist":"y","price":1}`},
+		{"missing price", "/albums/1", `{"title":"x","artist":"y"}`},
+	} {
+		if status, resp := do(t, srv, http.MethodPut, tt.path, tt.body); status != http.StatusBadRequest {
+			t.Errorf("PUT %s with %s = %d %q, want 400", tt.path, tt.name, status, resp)
+		}
+	}
+	if a, _ := albums.Get(context.Background(), 1); a.Title != "Album 1" {
+		t.Errorf("album 1 = %+v, want it unchanged by rejected updates", a)
+	}
+}
+
+func TestPatchAlbum(t *testing.T) {
+	albums := seed(3)
+	srv := newTestServer(t, albums)
+	patch := func(path, contentType, body string) (*http.Response, problem.Problem) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPatch, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var p problem.Problem
+		json.NewDecoder(resp.Body).Decode(&p)
+		return resp, p
+	}
+
+	if resp, _ := patch("/albums/2", album.MergePatchType, `{"title":"Kind of Blue"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("merge patch of album 2 = %d, want 200", resp.StatusCode)
+	}
+	if resp, _ := patch("/albums/2", album.JSONPatchType, `[{"op":"test","path":"/title","value":"Kind of Blue"},{"op":"replace","path":"/price","value":"29.99"}]`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("JSON Patch of album 2 = %d, want 200", resp.StatusCode)
+	}
+	stored, err := albums.Get(context.Background(), 2)
+	if err != nil || stored != (album.Album{ID: 2, Title: "Kind of Blue", Artist: "Miles Davis", Price: 2999, Version: 3}) {
+		t.Errorf("album 2 after PATCH = %+v, %v, want the new title and price 29.99", stored, err)
+	}
+
+	tests := []struct {
+		path, contentType, body string
+		status                  int
+		code                    problem.Code
+	}{
+		{"/albums/abc", album.MergePatchType, `{}`, http.StatusBadRequest, problem.InvalidID},
+		{"/albums/1", "text/plain", `title=x`, http.StatusUnsupportedMediaType, problem.UnsupportedMediaType},
+		{"/albums/1", album.MergePatchType, `{"id":7}`, http.StatusBadRequest, problem.InvalidBody},
+		{"/albums/1", album.MergePatchType, `{"title":null}`, http.StatusBadRequest, problem.ValidationFailed},
+		{"/albums/1", album.JSONPatchType, `[{"op":"test","path":"/price","value":1}]`, http.StatusConflict, problem.PatchTestFailed},
+		{"/albums/42", album.MergePatchType, `{"title":"x"}`, http.StatusNotFound, problem.NotFound},
+		{"/albums/42", album.JSONPatchType, `[]`, http.StatusNotFound, problem.NotFound},
+		{"/albums/1", album.MergePatchType, `{"title":"` + strings.Repeat("x", album.MaxPatchBytes) + `"}`, http.StatusRequestEntityTooLarge, problem.BodyTooLarge},
+	}
+	for _, tt := range tests {
+		resp, p := patch(tt.path, tt.contentType, tt.body)
+		if resp.StatusCode != tt.status || p.Code != tt.code {
+			t.Errorf("PATCH %s with %s %s = %d %s, want %d %s", tt.path, tt.contentType, tt.body, resp.StatusCode, p.Code, tt.status, tt.code)
+		}
+		if tt.status == http.StatusUnsupportedMediaType && resp.Header.Get("Accept-Patch") != album.AcceptPatch {
+			t.Errorf("PATCH with %s: Accept-Patch = %q, want %q", tt.contentType, resp.Header.Get("Accept-Patch"), album.AcceptPatch)
+		}
+	}
+	if a, _ := albums.Get(context.Background(), 1); a.Title != "Album 1" {
+		t.Errorf("album 1 = %+v, want it unchanged by rejected patches", a)
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	albums := seed(3)
+	srv := newTestServer(t, albums)
+	send := func(method, path, header, value, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(header, value)
+		if method == http.MethodPatch {
+			req.Header.Set("Content-Type", album.MergePatchType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return resp
+	}
+	const body = `{"title":"Kind of Blue","artist":"Miles Davis","price":29.99}`
+
+	resp := send(http.MethodGet, "/albums/1", "If-None-Match", "", "")
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag != `"1"` {
+		t.Fatalf("GET /albums/1 = %d with ETag %s, want 200 with ETag \"1\"", resp.StatusCode, etag)
+	}
+	tests := []struct {
+		method, path, header, value, body string
+		status                            int
+		etag                              string
+	}{
+		{http.MethodGet, "/albums/1", "If-None-Match", etag, "", http.StatusNotModified, `"1"`},
+		{http.MethodGet, "/albums/1", "If-None-Match", `"0"`, "", http.StatusOK, `"1"`},
+		{http.MethodPut, "/albums/1", "If-Match", etag, body, http.StatusOK, `"2"`},
+		// The album changed, so the ETag sent above is stale.
+		{http.MethodPut, "/albums/1", "If-Match", etag, body, http.StatusPreconditionFailed, ""},
+		{http.MethodPatch, "/albums/1", "If-Match", etag, `{"price":1}`, http.StatusPreconditionFailed, ""},
+		{http.MethodDelete, "/albums/1", "If-Match", etag, "", http.StatusPreconditionFailed, ""},
+		{http.MethodPatch, "/albums/1", "If-Match", `"2"`, `{"price":1}`, http.StatusOK, `"3"`},
+		{http.MethodPut, "/albums/2", "If-Match", "*", body, http.StatusOK, `"2"`},
+		{http.MethodPut, "/albums/42", "If-Match", "*", body, http.StatusNotFound, ""},
+		{http.MethodDelete, "/albums/1", "If-Match", `"2", "3"`, "", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		resp := send(tt.method, tt.path, tt.header, tt.value, tt.body)
+		if resp.StatusCode != tt.status || resp.Header.Get("ETag") != tt.etag {
+			t.Errorf("%s %s with %s: %s = %d with ETag %s, want %d with ETag %s", tt.method, tt.path, tt.header, tt.value, resp.StatusCode, resp.Header.Get("ETag"), tt.status, tt.etag)
+		}
+	}
+}
+
+func TestImportExport(t *testing.T) {
+	albums := seed(2)
+	srv := newTestServer(t, albums)
+	post := func(path, contentType, body string) (int, bulk.Report) {
+		t.Helper()
+		resp, err := http.Post(srv.URL+path, contentType, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var report bulk.Report
+		json.NewDecoder(resp.Body).Decode(&report)
+		return resp.StatusCode, report
+	}
+
+	const rows = "title,artist,price\nJeru,Gerry Mulligan,17.99\n,Nobody,1\nGiant Steps,John Coltrane,63.99\n"
+	if status, report := post("/albums:bulk", "text/csv", rows); status != http.StatusUnprocessableEntity || report.Created != 0 || report.Invalid != 1 {
+		t.Errorf("atomic import with an invalid row = %d %+v, want 422 with nothing created", status, report)
+	}
+	if n, _ := albums.Count(context.Background()); n != 2 {
+		t.Errorf("%d albums after a rejected atomic import, want 2", n)
+	}
+	status, report := post("/albums:bulk?mode=partial", "text/csv", rows)
+	if status != http.StatusOK || report.Created != 2 || report.Rows[0].ID != 3 || report.Rows[1].Status != bulk.Invalid || report.Rows[2].ID != 4 {
+		t.Errorf("partial import = %d %+v, want albums 3 and 4 created and row 2 invalid", status, report)
+	}
+	if status, report := post("/albums:bulk", "application/x-ndjson", `{"title":"Blue Train","artist":"John Coltrane","price":56.99}`); status != http.StatusOK || report.Created != 1 {
+		t.Errorf("NDJSON import = %d %+v, want 1 created", status, report)
+	}
+
+	for _, tt := range []struct {
+		path, contentType, body string
+		status                  int
+		code                    problem.Code
+	}{
+		{"/albums:bulk?mode=some", "application/json", `[]`, http.StatusBadRequest, problem.InvalidParameter},
+		{"/albums:bulk", "application/xml", `<albums/>`, http.StatusUnsupportedMediaType, problem.UnsupportedMediaType},
+		{"/albums:bulk", "application/json", `[{"title":"x",`, http.StatusBadRequest, problem.InvalidBody},
+	} {
+		resp, err := http.Post(srv.URL+tt.path, tt.contentType, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var p problem.Problem
+		json.NewDecoder(resp.Body).Decode(&p)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status || p.Code != tt.code {
+			t.Errorf("POST %s with %s = %d %s, want %d %s", tt.path, tt.contentType, resp.StatusCode, p.Code, tt.status, tt.code)
+		}
+	}
+
+	// An import over the limit is refused before it is read to the end.
+	req := httptest.NewRequest(http.MethodPost, "/albums:bulk", strings.NewReader("["+strings.Repeat(" ", bulk.MaxImportBytes)+"]"))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	srv.Config.Handler.ServeHTTP(rec, req)
+	var p problem.Problem
+	json.NewDecoder(rec.Body).Decode(&p)
+	if rec.Code != http.StatusRequestEntityTooLarge || p.Code != problem.BodyTooLarge {
+		t.Errorf("POST /albums:bulk over %d bytes = %d %s, want 413 %s", bulk.MaxImportBytes, rec.Code, p.Code, problem.BodyTooLarge)
+	}
+
+	resp, err := http.Get(srv.URL + "/albums:export?format=csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != bulk.CSVType || len(lines) != 6 || lines[5] != "5,Blue Train,John Coltrane,56.99" {
+		t.Errorf("GET /albums:export?format=csv = %d %s %q, want a header and 5 albums", resp.StatusCode, resp.Header.Get("Content-Type"), lines)
+	}
+	if status, _ := do(t, srv, http.MethodGet, "/albums:export?format=xml", ""); status != http.StatusBadRequest {
+		t.Errorf("GET /albums:export?format=xml = %d, want 400", status)
+	}
+	if status, body := do(t, newTestServer(t, classifiedRepository{err: album.ErrUnavailable}), http.MethodGet, "/albums:export", ""); status != http.StatusServiceUnavailable {
+		t.Errorf("export when the store is unavailable = %d %s, want 503", status, body)
+	}
+}
+
+func TestGetAlbumByID(t *testing.T) {
+	srv := newTestServer(t, seed(3))
+	status, body := do(t, srv, http.MethodGet, "/albums/2", "")
+	var a album.Album
+	json.Unmarshal(body, &a)
+	if status != http.StatusOK || a.ID != 2 || a.Artist != "Miles Davis" {
+		t.Errorf("GET /albums/2 = %d %s, want album 2", status, body)
+	}
+	if status, _ := do(t, srv, http.MethodGet, "/albums/42", ""); status != http.StatusNotFound {
+		t.Errorf("GET /albums/42 = %d, want 404", status)
+	}
+	if status, _ := do(t, srv, http.MethodGet, "/albums/abc", ""); status != http.StatusBadRequest {
+		t.Errorf("GET /albums/abc = %d, want 400", status)
+	}
+
+	failing := newTestServer(t, failingRepository{})
+	if status, _ := do(t, failing, http.MethodGet, "/albums/1", ""); status != http.StatusInternalServerError {
+		t.Errorf("GET /albums/1 with a failing store = %d, want 500", status)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	srv := newTestServer(t, seed(6))
+	tests := []struct {
+		path   string
+		status int
+		want   []int64
+	}{
+		{"/albums/name/album%201", http.StatusOK, []int64{1}},
+		{"/albums/name/nothing", http.StatusNotFound, nil},
+		{"/albums/artist/miles", http.StatusOK, []int64{2, 4, 6}},
+		{"/albums/artist/miles?limit=2&page=2", http.StatusOK, []int64{6}},
+		{"/albums/search?search=coltrane%20john", http.StatusOK, []int64{1, 3, 5}},
+		{"/albums/search", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		status, body := do(t, srv, http.MethodGet, tt.path, "")
+		if status != tt.status {
+			t.Errorf("GET %s = %d %q, want %d", tt.path, status, body, tt.status)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		if got := ids(t, body); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("GET %s = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestProblems(t *testing.T) {
+	srv := newTestServer(t, seed(1))
+	failing := newTestServer(t, failingRepository{})
+	tests := []struct {
+		srv    *httptest.Server
+		method string
+		path   string
+		body   string
+		status int
+		code   problem.Code
+	}{
+		{srv, http.MethodPost, "/albums", `{"artist":"x","price":-1}`, http.StatusBadRequest, problem.ValidationFailed},
+		{srv, http.MethodPost, "/albums", `[]`, http.StatusBadRequest, problem.InvalidBody},
+		{srv, http.MethodGet, "/albums/abc", "", http.StatusBadRequest, problem.InvalidID},
+		{srv, http.MethodDelete, "/albums/0", "", http.StatusBadRequest, problem.InvalidID},
+		{srv, http.MethodDelete, "/albums/-3", "", http.StatusBadRequest, problem.InvalidID},
+		{srv, http.MethodGet, "/albums?after=abc", "", http.StatusBadRequest, problem.InvalidCursor},
+		{srv, http.MethodGet, "/albums?limit=abc", "", http.StatusBadRequest, problem.InvalidPagination},
+		{srv, http.MethodGet, "/albums?limit=100&page=21474838", "", http.StatusBadRequest, problem.InvalidPagination},
+		{srv, http.MethodGet, "/albums/artist/miles?page=99999999999999999999", "", http.StatusBadRequest, problem.InvalidPagination},
+		{srv, http.MethodGet, "/albums?limit=0", "", http.StatusBadRequest, problem.InvalidPagination},
+		{srv, http.MethodGet, "/albums?limit=-1&after=", "", http.StatusBadRequest, problem.InvalidPagination},
+		{srv, http.MethodGet, "/albums/artist/miles?limit=101", "", http.StatusBadRequest, problem.InvalidPagination},
+		{srv, http.MethodGet, "/albums/search?search=x&limit=99999999999999999999", "", http.StatusBadRequest, problem.InvalidPagination},
+		{srv, http.MethodGet, "/albums/search", "", http.StatusBadRequest, problem.MissingParameter},
+		{srv, http.MethodGet, "/albums/9", "", http.StatusNotFound, problem.NotFound},
+		{failing, http.MethodGet, "/albums/1", "", http.StatusInternalServerError, problem.Internal},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, tt.srv.URL+tt.path, strings.NewReader(tt.body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		var p problem.Problem
+		json.Unmarshal(body, &p)
+		if resp.StatusCode != tt.status || p.Status != tt.status || p.Code != tt.code {
+			t.Errorf("%s %s = %d %s, want %d with code %s", tt.method, tt.path, resp.StatusCode, body, tt.status, tt.code)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != problem.ContentType || p.RequestID == "" || p.Instance != strings.Split(tt.path, "?")[0] {
+			t.Errorf("%s %s = %s %s, want a problem with a request ID and instance", tt.method, tt.path, ct, body)
+		}
+		if strings.Contains(string(body), errConnection.Error()) {
+			t.Errorf("%s %s leaked the database error: %s", tt.method, tt.path, body)
+		}
+		if tt.code == problem.ValidationFailed && (len(p.Errors) != 2 || p.Errors[0].Field != "title" || p.Errors[1].Code != "not_positive") {
+			t.Errorf("%s %s field errors = %+v, want title and price", tt.method, tt.path, p.Errors)
+		}
+	}
+}
+
+func TestStoreErrors(t *testing.T) {
+	const body = `{"title":"Blue Train","artist":"John Coltrane","price":56.99}`
+	tests := []struct {
+		err    error
+		status int
+		code   problem.Code
+	}{
+		{album.ErrNotFound, http.StatusNotFound, problem.NotFound},
+		{album.ErrConflict, http.StatusConflict, problem.Conflict},
+		{album.ErrConstraint, http.StatusUnprocessableEntity, problem.ConstraintViolation},
+		{album.ErrUnavailable, http.StatusServiceUnavailable, problem.Unavailable},
+		{errConnection, http.StatusInternalServerError, problem.Internal},
+	}
+	for _, tt := range tests {
+		srv := newTestServer(t, classifiedRepository{err: tt.err})
+		for _, req := range []struct{ method, path, body string }{
+			{http.MethodGet, "/albums/1", ""},
+			{http.MethodPut, "/albums/1", body},
+			{http.MethodDelete, "/albums/1", ""},
+			{http.MethodPost, "/albums", body},
+			{http.MethodGet, "/albums?after=", ""},
+		} {
+			status, resp := do(t, srv, req.method, req.path, req.body)
+			var p problem.Problem
+			json.Unmarshal(resp, &p)
+			if status != tt.status || p.Code != tt.code {
+				t.Errorf("%s %s when the store fails with %v = %d %s, want %d %s", req.method, req.path, tt.err, status, resp, tt.status, tt.code)
+			}
+		}
+	}
+}
+
+func TestDeleteAlbum(t *testing.T) {
+	srv := newTestServer(t, seed(2))
+	if status, body := do(t, srv, http.MethodDelete, "/albums/1", ""); status != http.StatusOK || !strings.Contains(string(body), "Album 1") {
+		t.Errorf("DELETE /albums/1 = %d %s, want 200 with the deleted album", status, body)
+	}
+	if status, _ := do(t, srv, http.MethodGet, "/albums/1", ""); status != http.StatusNotFound {
+		t.Errorf("GET /albums/1 after DELETE = %d, want 404", status)
+	}
+	if status, _ := do(t, srv, http.MethodDelete, "/albums/1", ""); status != http.StatusNotFound {
+		t.Errorf("DELETE /albums/1 twice = %d, want 404", status)
+	}
+	if status, _ := do(t, srv, http.MethodDelete, "/albums/abc", ""); status != http.StatusBadRequest {
+		t.Errorf("DELETE /albums/abc = %d, want 400", status)
+	}
+}
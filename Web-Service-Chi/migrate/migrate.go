@@ -0,0 +1,366 @@
+// Package migrate applies the numbered SQL migrations of a schema directory
+// to a Postgres database, recording each applied version and the checksum of
+// its file in the schema_migrations table.
+//
+// A migration file is named NNN_name.sql and holds an Up section and,
+// optionally, a Down section, in the sql-migrate format that sqlc also
+// understands:
+//
+//	-- +migrate Up
+//	CREATE TABLE ...;
+//
+//	-- +migrate Down
+//	DROP TABLE ...;
+package migrate
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors returned by a Migrator, wrapped with details. Check for them with
+// errors.Is.
+var (
+	// ErrModified means a migration file changed after it was applied.
+	ErrModified = errors.New("migration file changed after it was applied")
+	// ErrUnknownVersion means the database has a version no file describes,
+	// usually because it was migrated by a newer build.
+	ErrUnknownVersion = errors.New("applied migration has no file")
+	// ErrIrreversible means a migration to roll back has no Down section.
+	ErrIrreversible = errors.New("migration has no Down section")
+	// ErrNoChange means there was nothing to apply or roll back.
+	ErrNoChange = errors.New("no migration to run")
+)
+
+// Migration is one parsed migration file.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// Checksum is the hex SHA-256 of the whole file.
+	Checksum   string
+	reversible bool
+}
+
+// Status describes a migration as seen by the database.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	// Modified reports that the file no longer matches the applied checksum.
+	Modified bool
+	// Missing reports a version that was applied but has no file.
+	Missing bool
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.sql$`)
+
+// Load parses every *.sql file at the root of fsys, ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	var migrations []Migration
+	for _, file := range files {
+		match := fileName.FindStringSubmatch(file)
+		if match == nil {
+			return nil, fmt.Errorf("migration %s: name must look like 001_create_albums.sql", file)
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", file, err)
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		m, err := parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", file, err)
+		}
+		sum := sha256.Sum256(data)
+		m.Version, m.Name, m.Checksum = version, match[2], hex.EncodeToString(sum[:])
+		migrations = append(migrations, m)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return cmp.Compare(a.Version, b.Version) })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", migrations[i-1].Name, migrations[i].Name, migrations[i].Version)
+		}
+	}
+	return migrations, nil
+}
+
+// parse splits a file into its Up and Down sections. Text before the Up
+// marker is a comment for readers.
+func parse(text string) (Migration, error) {
+	var m Migration
+	var section *strings.Builder
+	var up, down strings.Builder
+	sawUp := false
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch strings.TrimSpace(line) {
+		case "-- +migrate Up":
+			if sawUp {
+				return m, errors.New("more than one Up section")
+			}
+			sawUp, section = true, &up
+			continue
+		case "-- +migrate Down":
+			if !sawUp || m.reversible {
+				return m, errors.New("the Down section must follow a single Up section")
+			}
+			m.reversible, section = true, &down
+			continue
+		}
+		if section != nil {
+			section.WriteString(line)
+			section.WriteByte('\n')
+		}
+	}
+	if !sawUp {
+		return m, errors.New("missing -- +migrate Up section")
+	}
+	m.Up, m.Down = strings.TrimSpace(up.String()), strings.TrimSpace(down.String())
+	return m, scanner.Err()
+}
+
+// hasStatements reports whether section contains more than comments.
+func hasStatements(section string) bool {
+	for line := range strings.Lines(section) {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "--") {
+			return true
+		}
+	}
+	return false
+}
+
+// Migrator applies migrations to one database.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New loads the migrations of fsys for db.
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// lockKey identifies the advisory lock that keeps two processes from
+// migrating at once.
+const lockKey = 7_262_019
+
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT PRIMARY KEY,
+	name TEXT NOT NULL,
+	checksum TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+)`
+
+// applied is a row of schema_migrations.
+type applied struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// session runs fn on one connection holding the migration lock, with the
+// applied versions loaded.
+func (m *Migrator) session(ctx context.Context, fn func(conn *sql.Conn, done map[int64]applied) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return fmt.Errorf("locking schema_migrations: %w", err)
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", lockKey)
+	if _, err := conn.ExecContext(ctx, createTable); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	done := make(map[int64]applied)
+	for rows.Next() {
+		var version int64
+		var a applied
+		if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return err
+		}
+		done[version] = a
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return fn(conn, done)
+}
+
+// verify checks that every applied migration still matches its file.
+func (m *Migrator) verify(done map[int64]applied) error {
+	for version, a := range done {
+		i := slices.IndexFunc(m.migrations, func(mig Migration) bool { return mig.Version == version })
+		if i < 0 {
+			return fmt.Errorf("version %d (%s): %w", version, a.name, ErrUnknownVersion)
+		}
+		if m.migrations[i].Checksum != a.checksum {
+			return fmt.Errorf("version %d (%s): %w", version, a.name, ErrModified)
+		}
+	}
+	return nil
+}
+
+// Up applies every pending migration in order, each in its own
+// transaction, and returns the ones it applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var ran []Migration
+	err := m.session(ctx, func(conn *sql.Conn, done map[int64]applied) error {
+		if err := m.verify(done); err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			if err := apply(ctx, conn, mig, true); err != nil {
+				return err
+			}
+			ran = append(ran, mig)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Down rolls back the most recently applied migration and returns it.
+func (m *Migrator) Down(ctx context.Context) (Migration, error) {
+	var mig Migration
+	err := m.session(ctx, func(conn *sql.Conn, done map[int64]applied) error {
+		var err error
+		if mig, err = m.latest(done); err != nil {
+			return err
+		}
+		return apply(ctx, conn, mig, false)
+	})
+	return mig, err
+}
+
+// Redo rolls back the most recently applied migration and applies it
+// again, which is handy while writing it.
+func (m *Migrator) Redo(ctx context.Context) (Migration, error) {
+	var mig Migration
+	err := m.session(ctx, func(conn *sql.Conn, done map[int64]applied) error {
+		var err error
+		if mig, err = m.latest(done); err != nil {
+			return err
+		}
+		if err := apply(ctx, conn, mig, false); err != nil {
+			return err
+		}
+		return apply(ctx, conn, mig, true)
+	})
+	return mig, err
+}
+
+// latest returns the applied migration with the highest version. Only its
+// file must exist: Redo is how a changed file gets re-applied.
+func (m *Migrator) latest(done map[int64]applied) (Migration, error) {
+	if len(done) == 0 {
+		return Migration{}, ErrNoChange
+	}
+	version := slices.Max(keys(done))
+	i := slices.IndexFunc(m.migrations, func(mig Migration) bool { return mig.Version == version })
+	if i < 0 {
+		return Migration{}, fmt.Errorf("version %d (%s): %w", version, done[version].name, ErrUnknownVersion)
+	}
+	mig := m.migrations[i]
+	if !mig.reversible {
+		return mig, fmt.Errorf("version %d (%s): %w", mig.Version, mig.Name, ErrIrreversible)
+	}
+	return mig, nil
+}
+
+func keys(done map[int64]applied) []int64 {
+	versions := make([]int64, 0, len(done))
+	for v := range done {
+		versions = append(versions, v)
+	}
+	return versions
+}
+
+// apply runs the Up or Down section of mig and updates schema_migrations in
+// one transaction.
+func apply(ctx context.Context, conn *sql.Conn, mig Migration, up bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	section, record := mig.Up, "INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)"
+	args := []any{mig.Version, mig.Name, mig.Checksum}
+	direction := "up"
+	if !up {
+		section, record = mig.Down, "DELETE FROM schema_migrations WHERE version = $1"
+		args = args[:1]
+		direction = "down"
+	}
+	if hasStatements(section) {
+		if _, err := tx.ExecContext(ctx, section); err != nil {
+			return fmt.Errorf("migration %d_%s %s: %w", mig.Version, mig.Name, direction, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return fmt.Errorf("recording migration %d_%s: %w", mig.Version, mig.Name, err)
+	}
+	return tx.Commit()
+}
+
+// Status lists every migration file and every applied version, ordered by
+// version.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.session(ctx, func(_ *sql.Conn, done map[int64]applied) error {
+		for _, mig := range m.migrations {
+			s := Status{Migration: mig}
+			if a, ok := done[mig.Version]; ok {
+				s.Applied, s.AppliedAt, s.Modified = true, a.appliedAt, a.checksum != mig.Checksum
+				delete(done, mig.Version)
+			}
+			statuses = append(statuses, s)
+		}
+		for version, a := range done {
+			statuses = append(statuses, Status{
+				Migration: Migration{Version: version, Name: a.name, Checksum: a.checksum},
+				Applied:   true, AppliedAt: a.appliedAt, Missing: true,
+			})
+		}
+		return nil
+	})
+	slices.SortFunc(statuses, func(a, b Status) int { return cmp.Compare(a.Version, b.Version) })
+	return statuses, err
+}
@@ -0,0 +1,95 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"dev.mfr/web-service-chi/schema"
+)
+
+func file(text string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(text)}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"010_add_index.sql": file("-- +migrate Up\nCREATE INDEX albums_artist ON albums (artist);\n"),
+		"002_albums.sql":    file("-- About albums.\n\n-- +migrate Up\nCREATE TABLE albums (id INT);\n\n-- +migrate Down\nDROP TABLE albums;\n"),
+		"schema.go":         file("package schema"),
+	}
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("Load returned %d migrations, want 2", len(migrations))
+	}
+	first, second := migrations[0], migrations[1]
+	if first.Version != 2 || first.Name != "albums" || second.Version != 10 || second.Name != "add_index" {
+		t.Errorf("Load order = %d_%s, %d_%s, want 2_albums, 10_add_index", first.Version, first.Name, second.Version, second.Name)
+	}
+	if first.Up != "CREATE TABLE albums (id INT);" || first.Down != "DROP TABLE albums;" || !first.reversible {
+		t.Errorf("2_albums sections = %q / %q, want the CREATE and DROP statements", first.Up, first.Down)
+	}
+	if second.reversible {
+		t.Error("10_add_index is reversible, want it irreversible without a Down section")
+	}
+	if len(first.Checksum) != 64 || first.Checksum == second.Checksum {
+		t.Errorf("checksums = %q, %q, want distinct SHA-256 hex digests", first.Checksum, second.Checksum)
+	}
+
+	fsys["002_albums.sql"] = file("-- +migrate Up\nCREATE TABLE albums (id BIGINT);\n")
+	changed, err := Load(fsys)
+	if err != nil || changed[0].Checksum == first.Checksum {
+		t.Errorf("checksum after editing the file = %q, %v, want it to change", changed[0].Checksum, err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"bad name":          {"albums.sql": file("-- +migrate Up\nSELECT 1;")},
+		"duplicate version": {"1_a.sql": file("-- +migrate Up\nSELECT 1;"), "001_b.sql": file("-- +migrate Up\nSELECT 1;")},
+		"no up section":     {"001_a.sql": file("SELECT 1;")},
+		"two up sections":   {"001_a.sql": file("-- +migrate Up\nSELECT 1;\n-- +migrate Up\nSELECT 2;")},
+		"down before up":    {"001_a.sql": file("-- +migrate Down\nSELECT 1;\n-- +migrate Up\nSELECT 2;")},
+	}
+	for name, fsys := range tests {
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("Load with %s = nil error, want an error", name)
+		}
+	}
+}
+
+func TestHasStatements(t *testing.T) {
+	for section, want := range map[string]bool{
+		"":                             false,
+		"-- nothing to undo\n\n":       false,
+		"-- drop it\nDROP TABLE a;":    true,
+		"  ALTER TABLE a ADD b INT;  ": true,
+	} {
+		if got := hasStatements(section); got != want {
+			t.Errorf("hasStatements(%q) = %v, want %v", section, got, want)
+		}
+	}
+}
+
+// The embedded schema must always load, since the service migrates with it
+// at startup.
+func TestSchemaFiles(t *testing.T) {
+	migrations, err := Load(schema.FS)
+	if err != nil {
+		t.Fatalf("Load(schema.FS): %v", err)
+	}
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %s has version %d, want %d: versions must be consecutive", m.Name, m.Version, i+1)
+		}
+		if !m.reversible {
+			t.Errorf("migration %d_%s has no Down section", m.Version, m.Name)
+		}
+		if !strings.Contains(m.Up, ";") {
+			t.Errorf("migration %d_%s has an empty Up section", m.Version, m.Name)
+		}
+	}
+}
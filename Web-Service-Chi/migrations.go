@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"dev.mfr/web-service-chi/migrate"
+	"dev.mfr/web-service-chi/schema"
+)
+
+const migrateUsage = `usage: web-service-chi migrate <command>
+
+Commands:
+  up      apply every pending migration in schema/
+  down    roll back the most recently applied migration
+  redo    roll back the most recent migration and apply it again
+  status  list migrations and whether they are applied`
+
+// migrateUp applies pending migrations before the server starts, so the
+// database always has the schema sqlc generated code against.
+func migrateUp(database *sql.DB) {
+	m, err := migrate.New(database, schema.FS)
+	if err != nil {
+		log.Fatalf("Error loading migrations: %v\n", err)
+	}
+	ran, err := m.Up(context.Background())
+	if err != nil {
+		log.Fatalf("Error migrating database: %v\n", err)
+	}
+	for _, mig := range ran {
+		fmt.Printf("Applied migration %03d_%s\n", mig.Version, mig.Name)
+	}
+}
+
+// runMigrate runs the migrate subcommand and returns the exit code.
+func runMigrate(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	database := openDatabase()
+	defer database.Close()
+	m, err := migrate.New(database, schema.FS)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading migrations: %v\n", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		var ran []migrate.Migration
+		ran, err = m.Up(ctx)
+		for _, mig := range ran {
+			fmt.Printf("Applied %03d_%s\n", mig.Version, mig.Name)
+		}
+		if err == nil && len(ran) == 0 {
+			fmt.Println("Database is up to date")
+		}
+	case "down", "redo":
+		run, verb := m.Down, "Rolled back"
+		if args[0] == "redo" {
+			run, verb = m.Redo, "Redid"
+		}
+		var mig migrate.Migration
+		mig, err = run(ctx)
+		switch {
+		case errors.Is(err, migrate.ErrNoChange):
+			fmt.Println("No migration has been applied")
+			err = nil
+		case err == nil:
+			fmt.Printf("%s %03d_%s\n", verb, mig.Version, mig.Name)
+		}
+	case "status":
+		var statuses []migrate.Status
+		if statuses, err = m.Status(ctx); err == nil {
+			printStatus(statuses)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running migrate %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func printStatus(statuses []migrate.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		status, at := "pending", ""
+		if s.Applied {
+			status, at = "applied", s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		switch {
+		case s.Missing:
+			status += " (file missing)"
+		case s.Modified:
+			status += " (file modified)"
+		}
+		fmt.Fprintf(w, "%03d\t%s\t%s\t%s\n", s.Version, s.Name, status, at)
+	}
+	w.Flush()
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"dev.mfr/album"
+	"dev.mfr/album/problem"
+)
+
+// listing holds the paging parameters of a list request. ?after= selects
+// keyset pagination, starting after the cursor it holds or at the first
+// album if empty; otherwise ?page= and ?limit= select an offset page.
+type listing struct {
+	limit int
+	page  int
+	after *album.Cursor // nil for offset paging
+}
+
+// maxLimit is the largest ?limit= a list request may ask for.
+const maxLimit = 100
+
+// parseListing reads the paging parameters of r, or returns the problem
+// with an invalid ?limit= or ?after=, or a ?page= too far to reach.
+func parseListing(r *http.Request) (listing, *problem.Problem) {
+	q := r.URL.Query()
+	l := listing{limit: 10} // Default limit
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxLimit {
+			return listing{}, problem.New(http.StatusBadRequest, problem.InvalidPagination, fmt.Sprintf("Limit must be between 1 and %d", maxLimit))
+		}
+		l.limit = n
+	}
+	if !q.Has("after") {
+		l.page, _ = strconv.Atoi(q.Get("page"))
+		if l.page <= 0 {
+			l.page = 1 // Default page
+		}
+		// The offset of the page is an int32 parameter of the queries.
+		if maxPage := math.MaxInt32/l.limit + 1; l.page > maxPage {
+			return listing{}, problem.New(http.StatusBadRequest, problem.InvalidPagination, fmt.Sprintf("Page must be at most %d for a limit of %d", maxPage, l.limit))
+		}
+		return l, nil
+	}
+	l.after = &album.Cursor{}
+	if token := q.Get("after"); token != "" {
+		c, err := album.ParseCursor(token)
+		if err != nil {
+			return listing{}, problem.New(http.StatusBadRequest, problem.InvalidCursor, "The after parameter is not a cursor from this service")
+		}
+		l.after = &c
+	}
+	return l, nil
+}
+
+// query returns the page to list. A keyset page asks for one more album
+// than it shows, to learn whether another page follows.
+func (l listing) query() album.Page {
+	if l.after != nil {
+		return album.Page{Limit: l.limit + 1, After: l.after.ID}
+	}
+	return album.Page{Limit: l.limit, Offset: (l.page - 1) * l.limit}
+}
+
+// count returns the total of an offset page. A keyset page has none, so
+// it skips the query, which visits every matching row.
+func (l listing) count(ctx context.Context, count func(context.Context) (int, error)) (int, error) {
+	if l.after != nil {
+		return 0, nil
+	}
+	return count(ctx)
+}
+
+// listPage is the response to every list request, in the same shape as
+// Web-Service-Gin's.
+type listPage struct {
+	Data []album.Album `json:"data"`
+	// Pagination is an offsetPagination or a cursorPagination.
+	Pagination any `json:"pagination"`
+}
+
+type offsetPagination struct {
+	Page       int  `json:"page"`
+	Limit      int  `json:"limit"`
+	Total      int  `json:"total"`
+	TotalPages int  `json:"total_pages"`
+	HasNext    bool `json:"has_next"`
+	HasPrev    bool `json:"has_prev"`
+}
+
+type cursorPagination struct {
+	Limit   int  `json:"limit"`
+	HasNext bool `json:"has_next"`
+	// NextCursor is the ?after= value of the next page, if any.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// write sends albums, the result of l.query(), in a listPage. A Link header
+// (RFC 8288) points to the first, previous, next and last pages that exist;
+// keyset pages only know the first and next ones.
+func (l listing) write(w http.ResponseWriter, r *http.Request, albums []album.Album, total int) error {
+	var page listPage
+	var links []string
+	if l.after != nil {
+		albums, next := album.NextCursor(albums, l.limit)
+		page = listPage{Data: albums, Pagination: cursorPagination{Limit: l.limit, HasNext: next != "", NextCursor: next}}
+		links = append(links, l.link(r.URL, "first", "after", ""))
+		if next != "" {
+			links = append(links, l.link(r.URL, "next", "after", next))
+		}
+	} else {
+		totalPages := (total + l.limit - 1) / l.limit // Ceiling division
+		p := offsetPagination{
+			Page:       l.page,
+			Limit:      l.limit,
+			Total:      total,
+			TotalPages: totalPages,
+			HasNext:    l.page < totalPages,
+			HasPrev:    l.page > 1,
+		}
+		page = listPage{Data: albums, Pagination: p}
+		links = append(links, l.link(r.URL, "first", "page", "1"))
+		if p.HasPrev {
+			links = append(links, l.link(r.URL, "prev", "page", strconv.Itoa(l.page-1)))
+		}
+		if p.HasNext {
+			links = append(links, l.link(r.URL, "next", "page", strconv.Itoa(l.page+1)))
+		}
+		if totalPages > 0 {
+			links = append(links, l.link(r.URL, "last", "page", strconv.Itoa(totalPages)))
+		}
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(page)
+}
+
+// link returns a link-value with relation rel to the request URL u, with
+// the limit in effect and key set to value.
+func (l listing) link(u *url.URL, rel, key, value string) string {
+	q := u.Query()
+	q.Set("limit", strconv.Itoa(l.limit))
+	q.Set(key, value)
+	target := url.URL{Path: u.Path, RawQuery: q.Encode()}
+	return fmt.Sprintf("<%s>; rel=%q", target.String(), rel)
+}
@@ -0,0 +1,269 @@
+package main
+
+//go:generate sqlc generate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+
+	"dev.mfr/album"
+	"dev.mfr/web-service-chi/db"
+)
+
+// postgresRepository is an album.AlbumRepository, album.Counter and
+// album.BulkCreator backed by the sqlc queries. Their errors go through
+// classify, so callers can tell a missing album or an unreachable database
+// from other failures.
+type postgresRepository struct {
+	db *sql.DB
+	q  *db.Queries
+}
+
+var (
+	_ album.AlbumRepository = (*postgresRepository)(nil)
+	_ album.Counter         = (*postgresRepository)(nil)
+	_ album.BulkCreator     = (*postgresRepository)(nil)
+)
+
+func newPostgresRepository(database *sql.DB) *postgresRepository {
+	return &postgresRepository{db: database, q: db.New(database)}
+}
+
+func (r *postgresRepository) Get(ctx context.Context, id int64) (album.Album, error) {
+	dbID, err := albumID(id)
+	if err != nil {
+		return album.Album{}, err
+	}
+	row, err := r.q.GetAlbumByID(ctx, dbID)
+	if err != nil {
+		return album.Album{}, fmt.Errorf("album %d: %w", id, classify(err))
+	}
+	return withVersion(toAlbum(row.ID, row.Title, row.Artist, row.Price), row.Version), nil
+}
+
+func (r *postgresRepository) List(ctx context.Context, page album.Page) ([]album.Album, error) {
+	if page.After > 0 {
+		return toAlbums(r.q.GetAlbumsAfter(ctx, db.GetAlbumsAfterParams{ID: afterID(page), Limit: pageLimit(page)}))
+	}
+	return toAlbums(r.q.GetAlbums(ctx, db.GetAlbumsParams{Limit: pageLimit(page), Offset: int32(page.Offset)}))
+}
+
+func (r *postgresRepository) Create(ctx context.Context, a album.Album) (album.Album, error) {
+	row, err := r.q.CreateAlbum(ctx, db.CreateAlbumParams{Title: a.Title, Artist: a.Artist, Price: a.Price})
+	if err != nil {
+		return album.Album{}, classify(err)
+	}
+	return withVersion(toAlbum(row.ID, row.Title, row.Artist, row.Price), row.Version), nil
+}
+
+// CreateAll inserts albums one by one in a single transaction.
+func (r *postgresRepository) CreateAll(ctx context.Context, albums []album.Album) ([]album.Album, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer tx.Rollback()
+	q := r.q.WithTx(tx)
+	created := make([]album.Album, len(albums))
+	for i, a := range albums {
+		row, err := q.CreateAlbum(ctx, db.CreateAlbumParams{Title: a.Title, Artist: a.Artist, Price: a.Price})
+		if err != nil {
+			return nil, fmt.Errorf("album %d of %d: %w", i+1, len(albums), classify(err))
+		}
+		created[i] = withVersion(toAlbum(row.ID, row.Title, row.Artist, row.Price), row.Version)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, classify(err)
+	}
+	return created, nil
+}
+
+func (r *postgresRepository) Update(ctx context.Context, a album.Album) (album.Album, error) {
+	dbID, err := albumID(a.ID)
+	if err != nil {
+		return album.Album{}, err
+	}
+	row, err := r.q.UpdateAlbum(ctx, db.UpdateAlbumParams{ID: dbID, Title: a.Title, Artist: a.Artist, Price: a.Price, Version: a.Version})
+	if err != nil {
+		return album.Album{}, r.unchanged(ctx, a.ID, a.Version, err)
+	}
+	return withVersion(toAlbum(row.ID, row.Title, row.Artist, row.Price), row.Version), nil
+}
+
+// Patch changes only the fields p sets, in a single UPDATE, so it does not
+// overwrite concurrent changes to the others.
+func (r *postgresRepository) Patch(ctx context.Context, id, version int64, p album.Patch) (album.Album, error) {
+	dbID, err := albumID(id)
+	if err != nil {
+		return album.Album{}, err
+	}
+	row, err := r.q.PatchAlbum(ctx, db.PatchAlbumParams{ID: dbID, Title: nullString(p.Title), Artist: nullString(p.Artist), Price: p.Price, Version: version})
+	if err != nil {
+		return album.Album{}, r.unchanged(ctx, id, version, err)
+	}
+	return withVersion(toAlbum(row.ID, row.Title, row.Artist, row.Price), row.Version), nil
+}
+
+func (r *postgresRepository) Delete(ctx context.Context, id, version int64) error {
+	dbID, err := albumID(id)
+	if err != nil {
+		return err
+	}
+	n, err := r.q.DeleteAlbum(ctx, db.DeleteAlbumParams{ID: dbID, Version: version})
+	if err != nil {
+		return fmt.Errorf("album %d: %w", id, classify(err))
+	}
+	if n == 0 {
+		return r.unchanged(ctx, id, version, sql.ErrNoRows)
+	}
+	return nil
+}
+
+// unchanged explains err, from a conditional change of the album with the
+// given ID that matched no row: the album is missing or, if it exists and
+// version is not zero, has another version.
+func (r *postgresRepository) unchanged(ctx context.Context, id, version int64, err error) error {
+	if !errors.Is(err, sql.ErrNoRows) || version == 0 {
+		return fmt.Errorf("album %d: %w", id, classify(err))
+	}
+	if _, err := r.Get(ctx, id); err != nil {
+		return err
+	}
+	return fmt.Errorf("album %d is not at version %d: %w", id, version, album.ErrVersionMismatch)
+}
+
+func (r *postgresRepository) SearchByTitle(ctx context.Context, title string, page album.Page) ([]album.Album, error) {
+	pattern := sql.NullString{String: title, Valid: true}
+	if page.After > 0 {
+		return toAlbums(r.q.GetAlbumByTitleAfter(ctx, db.GetAlbumByTitleAfterParams{
+			Title: pattern,
+			ID:    afterID(page),
+			Limit: pageLimit(page),
+		}))
+	}
+	return toAlbums(r.q.GetAlbumByTitle(ctx, db.GetAlbumByTitleParams{
+		Title:  pattern,
+		Limit:  pageLimit(page),
+		Offset: int32(page.Offset),
+	}))
+}
+
+func (r *postgresRepository) SearchByArtist(ctx context.Context, artist string, page album.Page) ([]album.Album, error) {
+	pattern := sql.NullString{String: artist, Valid: true}
+	if page.After > 0 {
+		return toAlbums(r.q.GetAlbumsByArtistAfter(ctx, db.GetAlbumsByArtistAfterParams{
+			Artist: pattern,
+			ID:     afterID(page),
+			Limit:  pageLimit(page),
+		}))
+	}
+	return toAlbums(r.q.GetAlbumsByArtist(ctx, db.GetAlbumsByArtistParams{
+		Artist: pattern,
+		Limit:  pageLimit(page),
+		Offset: int32(page.Offset),
+	}))
+}
+
+func (r *postgresRepository) FullText(ctx context.Context, query string, page album.Page) ([]album.Album, error) {
+	if page.After > 0 {
+		return toAlbums(r.q.GetAlbumsByFullTextSearchAfter(ctx, db.GetAlbumsByFullTextSearchAfterParams{
+			PlaintoTsquery: query,
+			ID:             afterID(page),
+			Limit:          pageLimit(page),
+		}))
+	}
+	return toAlbums(r.q.GetAlbumsByFullTextSearch(ctx, db.GetAlbumsByFullTextSearchParams{
+		PlaintoTsquery: query,
+		Limit:          pageLimit(page),
+		Offset:         int32(page.Offset),
+	}))
+}
+
+func (r *postgresRepository) Count(ctx context.Context) (int, error) {
+	n, err := r.q.CountAlbums(ctx)
+	return int(n), classify(err)
+}
+
+func (r *postgresRepository) CountByTitle(ctx context.Context, title string) (int, error) {
+	n, err := r.q.CountAlbumsByTitle(ctx, sql.NullString{String: title, Valid: true})
+	return int(n), classify(err)
+}
+
+func (r *postgresRepository) CountByArtist(ctx context.Context, artist string) (int, error) {
+	n, err := r.q.CountAlbumsByArtist(ctx, sql.NullString{String: artist, Valid: true})
+	return int(n), classify(err)
+}
+
+func (r *postgresRepository) CountFullText(ctx context.Context, query string) (int, error) {
+	n, err := r.q.CountAlbumsByFullTextSearch(ctx, query)
+	return int(n), classify(err)
+}
+
+// pageLimit converts page.Limit for a LIMIT parameter, where no limit is the
+// largest int32.
+func pageLimit(page album.Page) int32 {
+	if page.Limit <= 0 || page.Limit > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int32(page.Limit)
+}
+
+// albumID converts id for an id parameter. The id column is a SERIAL, so
+// an ID outside the int32 range names no album, rather than the one its
+// truncation would.
+func albumID(id int64) (int32, error) {
+	if id < 1 || id > math.MaxInt32 {
+		return 0, fmt.Errorf("album %d: %w", id, album.ErrNotFound)
+	}
+	return int32(id), nil
+}
+
+// afterID converts page.After for an id > parameter. The id column is a
+// SERIAL, so no ID lies above the largest int32.
+func afterID(page album.Page) int32 {
+	return int32(min(page.After, math.MaxInt32))
+}
+
+// albumRow is the underlying type of every sqlc row type listing albums.
+type albumRow = struct {
+	ID     int32       `json:"id"`
+	Title  string      `json:"title"`
+	Artist string      `json:"artist"`
+	Price  album.Money `json:"price"`
+}
+
+// toAlbums converts the result of a sqlc listing query.
+func toAlbums[R ~albumRow](rows []R, err error) ([]album.Album, error) {
+	if err != nil {
+		return nil, classify(err)
+	}
+	albums := make([]album.Album, len(rows))
+	for i, row := range rows {
+		r := albumRow(row)
+		albums[i] = toAlbum(r.ID, r.Title, r.Artist, r.Price)
+	}
+	return albums, nil
+}
+
+// toAlbum converts the columns of any of the sqlc album rows.
+func toAlbum(id int32, title, artist string, price album.Money) album.Album {
+	return album.Album{ID: int64(id), Title: title, Artist: artist, Price: price}
+}
+
+// withVersion sets the Version of a, for the rows that select it.
+func withVersion(a album.Album, version int64) album.Album {
+	a.Version = version
+	return a
+}
+
+// nullString converts an optional field of an album.Patch, where nil keeps
+// the column as it is.
+func nullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"testing"
+
+	"dev.mfr/album"
+	"dev.mfr/web-service-chi/db"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{sql.ErrNoRows, album.ErrNotFound},
+		{&pgconn.PgError{Code: "23505"}, album.ErrConflict},
+		{&pgconn.PgError{Code: "23514"}, album.ErrConstraint},
+		{&pgconn.PgError{Code: "22001"}, album.ErrConstraint},
+		{&pgconn.PgError{Code: "08006"}, album.ErrUnavailable},
+		{&pgconn.PgError{Code: "57P01"}, album.ErrUnavailable},
+		{fmt.Errorf("query: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")}), album.ErrUnavailable},
+		{driver.ErrBadConn, album.ErrUnavailable},
+		{sql.ErrConnDone, album.ErrUnavailable},
+	}
+	for _, tt := range tests {
+		got := classify(tt.err)
+		if !errors.Is(got, tt.want) || !errors.Is(got, tt.err) {
+			t.Errorf("classify(%v) = %v, want it to match %v and the original error", tt.err, got, tt.want)
+		}
+	}
+
+	syntax := &pgconn.PgError{Code: "42601"}
+	if got := classify(syntax); got != syntax {
+		t.Errorf("classify(syntax error) = %v, want it unchanged", got)
+	}
+	if classify(nil) != nil {
+		t.Error("classify(nil) != nil")
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(classify(&pgconn.PgError{Code: "23505", ConstraintName: "albums_title_key"}), &pgErr) || pgErr.ConstraintName != "albums_title_key" {
+		t.Errorf("errors.As on a classified error = %v, want the *pgconn.PgError", pgErr)
+	}
+}
+
+// execDB is a db.DBTX whose statements all return result and err.
+type execDB struct {
+	db.DBTX
+	result sql.Result
+	err    error
+}
+
+func (d execDB) ExecContext(context.Context, string, ...any) (sql.Result, error) {
+	return d.result, d.err
+}
+
+func TestPostgresDelete(t *testing.T) {
+	tests := []struct {
+		name string
+		db   execDB
+		want error
+	}{
+		{"deleted", execDB{result: driver.RowsAffected(1)}, nil},
+		{"missing", execDB{result: driver.RowsAffected(0)}, album.ErrNotFound},
+		{"connection lost", execDB{err: &pgconn.PgError{Code: "08006"}}, album.ErrUnavailable},
+	}
+	for _, tt := range tests {
+		r := &postgresRepository{q: db.New(tt.db)}
+		err := r.Delete(context.Background(), 7, 0)
+		if !errors.Is(err, tt.want) || (tt.want == nil) != (err == nil) {
+			t.Errorf("Delete when %s = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+// unusedDB is a db.DBTX that fails the test if a statement reaches it.
+type unusedDB struct {
+	db.DBTX
+	t *testing.T
+}
+
+func (d unusedDB) ExecContext(_ context.Context, query string, _ ...any) (sql.Result, error) {
+	d.t.Fatalf("ran %q", query)
+	return nil, nil
+}
+
+func (d unusedDB) QueryRowContext(_ context.Context, query string, _ ...any) *sql.Row {
+	d.t.Fatalf("ran %q", query)
+	return nil
+}
+
+func TestPostgresOutOfRangeID(t *testing.T) {
+	ctx := context.Background()
+	r := &postgresRepository{q: db.New(unusedDB{t: t})}
+	// 4294967297 would truncate to album 1.
+	for _, id := range []int64{0, -1, math.MaxInt32 + 1, 4294967297} {
+		if _, err := r.Get(ctx, id); !errors.Is(err, album.ErrNotFound) {
+			t.Errorf("Get(%d) = %v, want ErrNotFound", id, err)
+		}
+		if _, err := r.Update(ctx, album.Album{ID: id, Title: "x", Artist: "y", Price: 1}); !errors.Is(err, album.ErrNotFound) {
+			t.Errorf("Update(%d) = %v, want ErrNotFound", id, err)
+		}
+		if _, err := r.Patch(ctx, id, 0, album.Patch{}); !errors.Is(err, album.ErrNotFound) {
+			t.Errorf("Patch(%d) = %v, want ErrNotFound", id, err)
+		}
+		if err := r.Delete(ctx, id, 0); !errors.Is(err, album.ErrNotFound) {
+			t.Errorf("Delete(%d) = %v, want ErrNotFound", id, err)
+		}
+	}
+}
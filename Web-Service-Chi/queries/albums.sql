@@ -6,8 +6,16 @@ LIMIT $1
 OFFSET
     $2;
 
+-- name: GetAlbumsAfter :many
+SELECT id, title, artist, price
+FROM albums
+WHERE
+    id > $1
+ORDER BY id
+LIMIT $2;
+
 -- name: GetAlbumByID :one
-SELECT id, title, artist, price FROM albums WHERE id = $1;
+SELECT id, title, artist, price, version FROM albums WHERE id = $1;
 
 -- name: CreateAlbum :one
 INSERT INTO
@@ -17,24 +25,45 @@ RETURNING
     id,
     title,
     artist,
-    price;
+    price,
+    version;
 
 -- name: UpdateAlbum :one
 UPDATE albums
 SET
     title = $2,
     artist = $3,
-    price = $4
+    price = $4,
+    version = version + 1
 WHERE
     id = $1
+    AND (version = $5 OR $5 = 0)
+RETURNING
+    id,
+    title,
+    artist,
+    price,
+    version;
+
+-- name: PatchAlbum :one
+UPDATE albums
+SET
+    title = COALESCE(sqlc.narg(title), title),
+    artist = COALESCE(sqlc.narg(artist), artist),
+    price = COALESCE(sqlc.narg(price), price),
+    version = version + 1
+WHERE
+    id = sqlc.arg(id)
+    AND (version = sqlc.arg(version) OR sqlc.arg(version) = 0)
 RETURNING
     id,
     title,
     artist,
-    price;
+    price,
+    version;
 
--- name: DeleteAlbum :exec
-DELETE FROM albums WHERE id = $1;
+-- name: DeleteAlbum :execrows
+DELETE FROM albums WHERE id = $1 AND (version = $2 OR $2 = 0);
 
 -- name: GetAlbumByTitle :many
 SELECT id, title, artist, price
@@ -46,6 +75,15 @@ LIMIT $1
 OFFSET
     $2;
 
+-- name: GetAlbumByTitleAfter :many
+SELECT id, title, artist, price
+FROM albums
+WHERE
+    title ILIKE '%' || sqlc.arg (title) || '%'
+    AND id > $1
+ORDER BY id
+LIMIT $2;
+
 -- name: GetAlbumsByArtist :many
 SELECT id, title, artist, price
 FROM albums
@@ -56,6 +94,15 @@ LIMIT $1
 OFFSET
     $2;
 
+-- name: GetAlbumsByArtistAfter :many
+SELECT id, title, artist, price
+FROM albums
+WHERE
+    artist ILIKE '%' || sqlc.arg (artist) || '%'
+    AND id > $1
+ORDER BY id
+LIMIT $2;
+
 -- name: GetAlbumsByFullTextSearch :many
 SELECT id, title, artist, price
 FROM albums
@@ -67,4 +114,40 @@ WHERE
 ORDER BY id
 LIMIT $2
 OFFSET
-    $3;
\ No newline at end of file
+    $3;
+
+-- name: GetAlbumsByFullTextSearchAfter :many
+SELECT id, title, artist, price
+FROM albums
+WHERE
+    to_tsvector(
+        'english',
+        title || ' ' || artist
+    ) @@ plainto_tsquery($1)
+    AND id > $2
+ORDER BY id
+LIMIT $3;
+
+-- name: CountAlbums :one
+SELECT COUNT(*) FROM albums;
+
+-- name: CountAlbumsByTitle :one
+SELECT COUNT(*)
+FROM albums
+WHERE
+    title ILIKE '%' || sqlc.arg (title) || '%';
+
+-- name: CountAlbumsByArtist :one
+SELECT COUNT(*)
+FROM albums
+WHERE
+    artist ILIKE '%' || sqlc.arg (artist) || '%';
+
+-- name: CountAlbumsByFullTextSearch :one
+SELECT COUNT(*)
+FROM albums
+WHERE
+    to_tsvector(
+        'english',
+        title || ' ' || artist
+    ) @@ plainto_tsquery($1);
\ No newline at end of file
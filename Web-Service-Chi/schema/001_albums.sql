@@ -1,7 +1,11 @@
-CREATE TABLE albums (
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS albums (
     id SERIAL PRIMARY KEY,
     title TEXT NOT NULL,
     artist TEXT NOT NULL,
     price DECIMAL(10, 2) NOT NULL,
     created_at TIMESTAMP DEFAULT NOW()
-);
\ No newline at end of file
+);
+
+-- +migrate Down
+DROP TABLE albums;
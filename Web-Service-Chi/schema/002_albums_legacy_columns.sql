@@ -0,0 +1,11 @@
+-- Databases set up by the old createTables have VARCHAR(255) columns and no
+-- created_at; bring them in line with 001. This is a no-op on new databases.
+
+-- +migrate Up
+ALTER TABLE albums
+    ALTER COLUMN title TYPE TEXT,
+    ALTER COLUMN artist TYPE TEXT,
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMP DEFAULT NOW();
+
+-- +migrate Down
+-- Nothing to undo: 001 already defines these columns.
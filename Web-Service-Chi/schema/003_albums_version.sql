@@ -0,0 +1,8 @@
+-- Count the changes to every album, so clients can send its version back as
+-- an If-Match ETag and updates can refuse to overwrite a newer one.
+
+-- +migrate Up
+ALTER TABLE albums ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+
+-- +migrate Down
+ALTER TABLE albums DROP COLUMN version;
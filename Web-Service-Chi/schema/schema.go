@@ -0,0 +1,10 @@
+// Package schema embeds the numbered migration files of the albums
+// database. sqlc reads the same files, skipping their Down sections.
+package schema
+
+import "embed"
+
+// FS holds every NNN_name.sql migration file.
+//
+//go:embed *.sql
+var FS embed.FS
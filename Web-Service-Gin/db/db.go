@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -68,6 +69,24 @@ func (d *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
 	return d.DB.Exec(query, args...)
 }
 
+// QueryRowContext is QueryRow with a context.
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	log.Printf("Executing query: %s with args: %v", query, args)
+	return d.DB.QueryRowContext(ctx, query, args...)
+}
+
+// QueryContext is Query with a context.
+func (d *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	log.Printf("Executing query: %s with args: %v", query, args)
+	return d.DB.QueryContext(ctx, query, args...)
+}
+
+// ExecContext is Exec with a context.
+func (d *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	log.Printf("Executing exec query: %s with args: %v", query, args)
+	return d.DB.ExecContext(ctx, query, args...)
+}
+
 // GetTables returns a list of all tables in the database.
 func (d *DB) GetTables() ([]string, error) {
 	rows, err := d.Query("SHOW TABLES")
@@ -95,7 +114,13 @@ func (d *DB) GetTables() ([]string, error) {
 // Transaction executes a function within a database transaction.
 // If the function returns an error, the transaction is rolled back. Otherwise, it's committed.
 func (d *DB) Transaction(fn func(*sql.Tx) error) error {
-	tx, err := d.Begin()
+	return d.TransactionContext(context.Background(), fn)
+}
+
+// TransactionContext is Transaction with a context. The transaction is rolled
+// back if ctx is done before it commits.
+func (d *DB) TransactionContext(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := d.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("could not begin transaction: %w", err)
 	}
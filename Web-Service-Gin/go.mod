@@ -2,10 +2,16 @@ module dev.mfr/web-service-gin
 
 go 1.24.5
 
+replace dev.mfr/album => ../album
+
 replace dev.mfr/db => ./db
 
+replace dev.mfr/go-routine/leakcheck => ../Go-Routine/leakcheck
+
 require (
+	dev.mfr/album v0.0.0-00010101000000-000000000000
 	dev.mfr/db v0.0.0-20241001000000-000000000000
+	dev.mfr/go-routine/leakcheck v0.0.0-00010101000000-000000000000
 	github.com/gin-gonic/gin v1.10.1
 	github.com/joho/godotenv v1.5.1
 )
@@ -1,31 +1,38 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
 
+	"dev.mfr/album"
+	"dev.mfr/album/bulk"
+	"dev.mfr/album/problem"
 	"dev.mfr/db"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
-type Album struct {
-	ID     int     `json:"id"`
-	Title  string  `json:"title"`
-	Artist string  `json:"artist"`
-	Price  float64 `json:"price"`
-}
-
 //	var albums = []Album{
 //		{ID: 1, Title: "Album One", Artist: "Genjirou", Price: 9.99},
 //		{ID: 2, Title: "Album Two", Artist: "GenjirouHD", Price: 14.99},
 //		{ID: 3, Title: "Album Three", Artist: "Genjirou", Price: 19.99},
 //	}
-var database *db.DB
-var err error
+
+// albumStore is everything repo must support: CRUD, counts and bulk inserts.
+type albumStore interface {
+	album.AlbumRepository
+	album.Counter
+	album.BulkCreator
+}
+
+var repo albumStore
 
 func main() {
 
@@ -34,52 +41,142 @@ func main() {
 		fmt.Println("Error loading .env file")
 		return
 	}
-	// Get database connection details from environment variables
-	dbConfig := db.Config{
-		User:     os.Getenv("DB_USER"),
-		Password: os.Getenv("DB_PASSWORD"),
-		Host:     os.Getenv("DB_HOST"),
-		Port:     os.Getenv("DB_PORT"),
-		DBName:   os.Getenv("DB_NAME"),
-	}
 
-	// Create a new database connection pool
-	database, err = db.New(dbConfig)
-	if err != nil {
-		fmt.Println("Error connecting to database:", err)
-		return
+	// ALBUM_STORE selects the backend: mysql (the default) or memory.
+	switch store := os.Getenv("ALBUM_STORE"); store {
+	case "memory":
+		repo = album.NewMemoryRepository()
+		fmt.Println("Using the in-memory album store")
+	case "", "mysql":
+		// Get database connection details from environment variables
+		dbConfig := db.Config{
+			User:     os.Getenv("DB_USER"),
+			Password: os.Getenv("DB_PASSWORD"),
+			Host:     os.Getenv("DB_HOST"),
+			Port:     os.Getenv("DB_PORT"),
+			DBName:   os.Getenv("DB_NAME"),
+		}
+
+		// Create a new database connection pool
+		database, err := db.New(dbConfig)
+		if err != nil {
+			fmt.Println("Error connecting to database:", err)
+			return
+		}
+		defer database.Close()
+		fmt.Println("Connected to database successfully!")
+		mysqlRepo := newMySQLRepository(database)
+		if err := mysqlRepo.checkSchema(context.Background()); err != nil {
+			fmt.Println("Error checking database schema:", err)
+			return
+		}
+		repo = mysqlRepo
+	default:
+		log.Fatalf("Unknown ALBUM_STORE %q, want mysql or memory", store)
 	}
-	defer database.Close()
-	fmt.Println("Connected to database successfully!")
 
+	router := setupRouter()
+	router.Run("localhost:8080")
+	fmt.Println("Server running on http://localhost:8080")
+
+}
+
+// setupRouter registers every album route on a new gin engine.
+func setupRouter() *gin.Engine {
 	router := gin.Default()
+	router.Use(requestID())
 	router.GET("/albums", getAlbums)
 	router.GET("/albums/:id", getAlbumByID)
 	router.GET("/albums/name/:name", GetAlbumByName)
 	router.POST("/albums/", AddAlbum)
 	router.PUT("/albums/:id", updateAlbum)
+	router.PATCH("/albums/:id", patchAlbum)
 	router.DELETE("/albums/:id", deleteAlbum)
 	router.GET("/albums/search", FindAlbumByFullTextSearch)
+	// Gin reads ":" in a route as the start of a parameter, so the custom
+	// methods /albums:bulk and /albums:export are routed through one.
+	router.POST("/albums:method", albumMethod(map[string]gin.HandlerFunc{":bulk": importAlbums}))
+	router.GET("/albums:method", albumMethod(map[string]gin.HandlerFunc{":export": exportAlbums}))
+	return router
+}
 
-	router.Run("localhost:8080")
-	fmt.Println("Server running on http://localhost:8080")
+// albumMethod picks the handler of the custom method in the path of the
+// request, such as ":bulk" for /albums:bulk.
+func albumMethod(handlers map[string]gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handler, ok := handlers[c.Param("method")]
+		if !ok {
+			abortWithProblem(c, problem.New(http.StatusNotFound, problem.NotFound, "No such endpoint"))
+			return
+		}
+		handler(c)
+	}
+}
 
+// requestIDHeader carries the ID of a request, set by the client or made up
+// by requestID.
+const requestIDHeader = "X-Request-Id"
+
+// requestID gives every request an ID, which problems include and the
+// response echoes.
+func requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if id == "" {
+			id = rand.Text()
+		}
+		c.Set(requestIDHeader, id)
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+// abortWithProblem sends p in response to the request of c.
+func abortWithProblem(c *gin.Context, p *problem.Problem) {
+	p.Write(c.Writer, c.Request, c.GetString(requestIDHeader))
+	c.Abort()
+}
+
+// internalError aborts with a plain 500, logging err for the operator.
+func internalError(c *gin.Context, msg string, err error) {
+	log.Printf("%s: %v [request %s]", msg, err, c.GetString(requestIDHeader))
+	abortWithProblem(c, problem.InternalError())
+}
+
+// storeError sends the problem for err, returned by the album store: 404 for
+// a missing album, 412 for a version mismatch or else a 500.
+func storeError(c *gin.Context, msg string, err error) {
+	switch {
+	case errors.Is(err, album.ErrNotFound):
+		abortWithProblem(c, problem.New(http.StatusNotFound, problem.NotFound, "Album not found"))
+	case errors.Is(err, album.ErrVersionMismatch):
+		abortWithProblem(c, problem.New(http.StatusPreconditionFailed, problem.PreconditionFailed, "The album was changed by another request"))
+	default:
+		internalError(c, msg, err)
+	}
 }
 
 func getAlbums(c *gin.Context) {
+	if after, ok := c.GetQuery("after"); ok {
+		listAfter(c, after, func(ctx context.Context, page album.Page) ([]album.Album, error) {
+			return repo.List(ctx, page)
+		})
+		return
+	}
+
 	// Get pagination parameters from query string
 	pageStr := c.DefaultQuery("page", "1")
 	limitStr := c.DefaultQuery("limit", "10")
 
 	page, err := strconv.Atoi(pageStr)
 	if err != nil || page < 1 {
-		c.JSON(400, gin.H{"error": "Invalid page number"})
+		abortWithProblem(c, problem.New(http.StatusBadRequest, problem.InvalidPagination, "Page must be a positive integer"))
 		return
 	}
 
 	limit, err := strconv.Atoi(limitStr)
 	if err != nil || limit < 1 || limit > 100 {
-		c.JSON(400, gin.H{"error": "Invalid limit (1-100)"})
+		abortWithProblem(c, problem.New(http.StatusBadRequest, problem.InvalidPagination, "Limit must be between 1 and 100"))
 		return
 	}
 
@@ -87,30 +184,18 @@ func getAlbums(c *gin.Context) {
 	offset := (page - 1) * limit
 
 	// Get total count
-	var total int
-	countRow := database.QueryRow("SELECT COUNT(*) FROM albums")
-	if err := countRow.Scan(&total); err != nil {
-		c.JSON(500, gin.H{"error": "Failed to count albums"})
+	total, err := repo.Count(c.Request.Context())
+	if err != nil {
+		internalError(c, "Failed to count albums", err)
 		return
 	}
 
 	// Get paginated albums
-	var albums []Album
-	albm, err := database.Query("SELECT id, title, artist, price FROM albums LIMIT ? OFFSET ?", limit, offset)
+	albums, err := repo.List(c.Request.Context(), album.Page{Limit: limit, Offset: offset})
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to fetch albums"})
+		internalError(c, "Failed to fetch albums", err)
 		return
 	}
-	defer albm.Close()
-
-	for albm.Next() {
-		var album Album
-		if err := albm.Scan(&album.ID, &album.Title, &album.Artist, &album.Price); err != nil {
-			c.JSON(500, gin.H{"error": "Failed to scan album"})
-			return
-		}
-		albums = append(albums, album)
-	}
 
 	// Calculate pagination metadata
 	totalPages := (total + limit - 1) / limit // Ceiling division
@@ -131,20 +216,73 @@ func getAlbums(c *gin.Context) {
 	})
 }
 
+// listAfter serves a page of a keyset-paginated listing: up to ?limit=
+// albums from list after the cursor after, or from the first album if after
+// is empty. Its pagination holds the next_cursor to pass as ?after= for the
+// next page, if there is one, instead of page numbers and totals.
+func listAfter(c *gin.Context, after string, list func(context.Context, album.Page) ([]album.Album, error)) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 100 {
+		abortWithProblem(c, problem.New(http.StatusBadRequest, problem.InvalidPagination, "Limit must be between 1 and 100"))
+		return
+	}
+	var cursor album.Cursor
+	if after != "" {
+		if cursor, err = album.ParseCursor(after); err != nil {
+			abortWithProblem(c, problem.New(http.StatusBadRequest, problem.InvalidCursor, "The after parameter is not a cursor from this service"))
+			return
+		}
+	}
+
+	// One album more than the limit tells whether another page follows.
+	albums, err := list(c.Request.Context(), album.Page{Limit: limit + 1, After: cursor.ID})
+	if err != nil {
+		internalError(c, "Failed to fetch albums", err)
+		return
+	}
+	albums, next := album.NextCursor(albums, limit)
+
+	pagination := gin.H{
+		"limit":    limit,
+		"has_next": next != "",
+	}
+	if next != "" {
+		pagination["next_cursor"] = next
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"data":       albums,
+		"pagination": pagination,
+	})
+}
+
 func getAlbumByID(c *gin.Context) {
-	id := c.Param("id")
-	var album Album
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		abortWithProblem(c, problem.New(http.StatusBadRequest, problem.InvalidID, "Album ID must be an integer"))
+		return
+	}
 
-	row := database.QueryRow("SELECT id, title, artist, price FROM albums WHERE id = ?", id)
-	if err := row.Scan(&album.ID, &album.Title, &album.Artist, &album.Price); err != nil {
-		c.JSON(404, gin.H{"error": "Album not found"})
+	alb, err := repo.Get(c.Request.Context(), id)
+	if err != nil {
+		storeError(c, "Failed to fetch album", err)
+		return
+	}
+	c.Header("ETag", alb.ETag())
+	if !album.IfNoneMatch(c.GetHeader("If-None-Match"), alb) {
+		c.Status(http.StatusNotModified)
 		return
 	}
-	c.IndentedJSON(http.StatusOK, album)
+	c.IndentedJSON(http.StatusOK, alb)
 }
 
 func GetAlbumByName(c *gin.Context) {
 	name := c.Param("name")
+	if after, ok := c.GetQuery("after"); ok {
+		listAfter(c, after, func(ctx context.Context, page album.Page) ([]album.Album, error) {
+			return repo.SearchByTitle(ctx, name, page)
+		})
+		return
+	}
 	pageStr := c.DefaultQuery("page", "1")
 	limitStr := c.DefaultQuery("limit", "10")
 
@@ -159,29 +297,19 @@ func GetAlbumByName(c *gin.Context) {
 	}
 
 	offset := (page - 1) * limit
-	searchTerm := "%" + name + "%"
 
 	// Get total count for this search
-	var total int
-	countRow := database.QueryRow("SELECT COUNT(*) FROM albums WHERE title LIKE ?", searchTerm)
-	countRow.Scan(&total)
-
-	// Get paginated results
-	var albums []Album
-	rows, err := database.Query("SELECT id, title, artist, price FROM albums WHERE title LIKE ? LIMIT ? OFFSET ?", searchTerm, limit, offset)
+	total, err := repo.CountByTitle(c.Request.Context(), name)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to fetch albums"})
+		internalError(c, "Failed to count albums", err)
 		return
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var album Album
-		if err := rows.Scan(&album.ID, &album.Title, &album.Artist, &album.Price); err != nil {
-			fmt.Println("Error scanning row:", err)
-			continue
-		}
-		albums = append(albums, album)
+	// Get paginated results
+	albums, err := repo.SearchByTitle(c.Request.Context(), name, album.Page{Limit: limit, Offset: offset})
+	if err != nil {
+		internalError(c, "Failed to fetch albums", err)
+		return
 	}
 
 	totalPages := (total + limit - 1) / limit
@@ -200,102 +328,199 @@ func GetAlbumByName(c *gin.Context) {
 }
 
 func AddAlbum(c *gin.Context) {
-	var newAlbum Album
-	if err := c.BindJSON(&newAlbum); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid album data"})
+	var newAlbum album.Album
+	if err := c.ShouldBindJSON(&newAlbum); err != nil {
+		abortWithProblem(c, problem.New(http.StatusBadRequest, problem.InvalidBody, fmt.Sprintf("Invalid album data: %v", err)))
+		return
+	}
+	if err := newAlbum.Validate(); err != nil {
+		abortWithProblem(c, problem.Validation(err))
 		return
 	}
 
-	result, err := database.Exec("INSERT INTO albums (title, artist, price) VALUES (?, ?, ?)", newAlbum.Title, newAlbum.Artist, newAlbum.Price)
+	newAlbum, err := repo.Create(c.Request.Context(), newAlbum)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to add album"})
+		internalError(c, "Failed to add album", err)
 		return
 	}
 
-	id, err := result.LastInsertId()
+	c.IndentedJSON(http.StatusCreated, newAlbum)
+}
+
+// importAlbums handles POST /albums:bulk, in one transaction unless
+// ?mode=partial, and reports on every row.
+func importAlbums(c *gin.Context) {
+	mode, err := bulk.ParseMode(c.Query("mode"))
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to retrieve album ID"})
+		abortWithProblem(c, problem.New(http.StatusBadRequest, problem.InvalidParameter, err.Error()))
+		return
+	}
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, bulk.MaxImportBytes)
+	dec, err := bulk.NewDecoder(c.ContentType(), body)
+	if err != nil {
+		abortWithProblem(c, problem.New(http.StatusUnsupportedMediaType, problem.UnsupportedMediaType, err.Error()))
 		return
 	}
-	newAlbum.ID = int(id)
 
-	c.IndentedJSON(http.StatusCreated, newAlbum)
+	report, err := bulk.Import(c.Request.Context(), dec, mode, repo)
+	if errors.Is(err, bulk.ErrTooLarge) {
+		abortWithProblem(c, problem.New(http.StatusRequestEntityTooLarge, problem.BodyTooLarge, err.Error()))
+		return
+	}
+	if errors.Is(err, bulk.ErrMalformed) {
+		abortWithProblem(c, problem.New(http.StatusBadRequest, problem.InvalidBody, err.Error()))
+		return
+	}
+	if err != nil {
+		internalError(c, "Failed to import albums", err)
+		return
+	}
+
+	c.JSON(report.StatusCode(), report)
+}
+
+// exportAlbums handles GET /albums:export?format=ndjson|csv.
+func exportAlbums(c *gin.Context) {
+	format := c.DefaultQuery("format", "ndjson")
+	enc, err := bulk.NewEncoder(format, c.Writer)
+	if err != nil {
+		abortWithProblem(c, problem.New(http.StatusBadRequest, problem.InvalidParameter, err.Error()))
+		return
+	}
+
+	c.Header("Content-Type", enc.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=albums.%s", format))
+	n, err := bulk.Export(c.Request.Context(), enc, func(ctx context.Context, page album.Page) ([]album.Album, error) {
+		return repo.List(ctx, page)
+	})
+	if err != nil && n == 0 {
+		c.Writer.Header().Del("Content-Disposition")
+		internalError(c, "Failed to export albums", err)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to export albums after %d: %v [request %s]", n, err, c.GetString(requestIDHeader))
+	}
 }
 
 func updateAlbum(c *gin.Context) {
 	id := c.Param("id")
-	var updatedAlbum Album
-	if err := c.BindJSON(&updatedAlbum); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid album data"})
+	var updatedAlbum album.Album
+	if err := c.ShouldBindJSON(&updatedAlbum); err != nil {
+		abortWithProblem(c, problem.New(http.StatusBadRequest, problem.InvalidBody, fmt.Sprintf("Invalid album data: %v", err)))
+		return
+	}
+	if err := updatedAlbum.Validate(); err != nil {
+		abortWithProblem(c, problem.Validation(err))
 		return
 	}
 
 	integerid, err := strconv.Atoi(id)
 	if err != nil {
-		c.JSON(400, gin.H{"error": "Invalid album ID"})
+		abortWithProblem(c, problem.New(http.StatusBadRequest, problem.InvalidID, "Album ID must be an integer"))
 		return
 	}
-	updatedAlbum.ID = integerid
-
-	result, err := database.Exec("UPDATE albums SET title = ?, artist = ?, price = ? WHERE id = ?", updatedAlbum.Title, updatedAlbum.Artist, updatedAlbum.Price, id)
+	updatedAlbum.ID = int64(integerid)
+	updatedAlbum.Version, err = album.Precondition(c.GetHeader("If-Match"), func() (album.Album, error) {
+		return repo.Get(c.Request.Context(), updatedAlbum.ID)
+	})
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to update album"})
+		storeError(c, "Failed to fetch album", err)
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil || rowsAffected == 0 {
-		c.JSON(404, gin.H{"error": "Album not found"})
+	updatedAlbum, err = repo.Update(c.Request.Context(), updatedAlbum)
+	if err != nil {
+		storeError(c, "Failed to update album", err)
 		return
 	}
 
+	c.Header("ETag", updatedAlbum.ETag())
 	c.IndentedJSON(http.StatusOK, updatedAlbum)
 }
-func deleteAlbum(c *gin.Context) {
-	id := c.Param("id")
-	var album Album
-	row := database.QueryRow("SELECT id, title, artist, price FROM albums WHERE id = ?", id)
 
-	if err := row.Scan(&album.ID, &album.Title, &album.Artist, &album.Price); err != nil {
-		c.JSON(404, gin.H{"error": "Album not found"})
+// patchAlbum handles PATCH /albums/:id with either kind of patch document.
+func patchAlbum(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		abortWithProblem(c, problem.New(http.StatusBadRequest, problem.InvalidID, "Album ID must be an integer"))
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, album.MaxPatchBytes)
+	body, err := c.GetRawData()
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		abortWithProblem(c, problem.New(http.StatusRequestEntityTooLarge, problem.BodyTooLarge, fmt.Sprintf("Patch must be at most %d bytes", tooLarge.Limit)))
+		return
+	}
+	if err != nil {
+		abortWithProblem(c, problem.New(http.StatusBadRequest, problem.InvalidBody, fmt.Sprintf("Invalid patch: %v", err)))
+		return
+	}
+	patch, version, err := album.ConditionalPatch(c.GetHeader("If-Match"), c.ContentType(), body, func() (album.Album, error) {
+		return repo.Get(c.Request.Context(), id)
+	})
+	if p := problem.Patch(err); p != nil {
+		if p.Status == http.StatusUnsupportedMediaType {
+			c.Header("Accept-Patch", album.AcceptPatch)
+		}
+		abortWithProblem(c, p)
 		return
 	}
 
-	result, err := database.Exec("DELETE FROM albums WHERE id = ?", id)
+	// Any other err is the store's, from fetching the album.
+	var patchedAlbum album.Album
+	if err == nil {
+		if err := patch.Validate(); err != nil {
+			abortWithProblem(c, problem.Validation(err))
+			return
+		}
+		patchedAlbum, err = repo.Patch(c.Request.Context(), id, version, patch)
+	}
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to delete album"})
+		storeError(c, "Failed to patch album", err)
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil || rowsAffected == 0 {
-		c.JSON(404, gin.H{"error": "Album not found"})
+	c.Header("ETag", patchedAlbum.ETag())
+	c.IndentedJSON(http.StatusOK, patchedAlbum)
+}
+func deleteAlbum(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		abortWithProblem(c, problem.New(http.StatusBadRequest, problem.InvalidID, "Album ID must be an integer"))
 		return
 	}
 
-	c.IndentedJSON(http.StatusOK, gin.H{"message": "Album deleted successfully", "album": album})
+	alb, err := repo.Get(c.Request.Context(), id)
+	if err != nil {
+		storeError(c, "Failed to fetch album", err)
+		return
+	}
+	version, err := album.Precondition(c.GetHeader("If-Match"), func() (album.Album, error) { return alb, nil })
+	if err != nil {
+		storeError(c, "Failed to check If-Match", err)
+		return
+	}
+
+	err = repo.Delete(c.Request.Context(), id, version)
+	if err != nil {
+		storeError(c, "Failed to delete album", err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "Album deleted successfully", "album": alb})
 }
 func FindAlbumByFullTextSearch(c *gin.Context) {
 	query := c.Query("q")
 	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'q' is required"})
+		abortWithProblem(c, problem.New(http.StatusBadRequest, problem.MissingParameter, "Query parameter 'q' is required"))
 		return
 	}
-	var albums []Album
-	rows, err := database.Query("SELECT id, title, artist, price FROM albums WHERE MATCH(title, artist) AGAINST(? IN NATURAL LANGUAGE MODE)", query)
+	albums, err := repo.FullText(c.Request.Context(), query, album.Page{})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search albums"})
+		internalError(c, "Failed to search albums", err)
 		return
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var album Album
-		if err := rows.Scan(&album.ID, &album.Title, &album.Artist, &album.Price); err != nil {
-			fmt.Println("Error scanning row:", err)
-			continue
-		}
-		albums = append(albums, album)
-	}
 	c.IndentedJSON(http.StatusOK, albums)
 }
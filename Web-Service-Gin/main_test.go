@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dev.mfr/album"
+	"dev.mfr/album/bulk"
+	"dev.mfr/album/problem"
+	"dev.mfr/go-routine/leakcheck"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	leakcheck.VerifyTestMain(m)
+}
+
+// The cases below are rejected before the database is used.
+func TestBadRequests(t *testing.T) {
+	leakcheck.Check(t)
+	srv := httptest.NewServer(setupRouter())
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		method, path, body string
+		code               problem.Code
+	}{
+		{http.MethodGet, "/albums?page=0", "", problem.InvalidPagination},
+		{http.MethodGet, "/albums?limit=101", "", problem.InvalidPagination},
+		{http.MethodGet, "/albums/search", "", problem.MissingParameter},
+		{http.MethodGet, "/albums?after=abc", "", problem.InvalidCursor},
+		{http.MethodGet, "/albums/name/x?after=&limit=0", "", problem.InvalidPagination},
+		{http.MethodGet, "/albums/abc", "", problem.InvalidID},
+		{http.MethodPost, "/albums/", "{", problem.InvalidBody},
+		{http.MethodPost, "/albums/", `{"title":"x","artist":"y","price":0}`, problem.ValidationFailed},
+		{http.MethodPost, "/albums/", `{"title":"x","artist":"y","price":9.999}`, problem.InvalidBody},
+		{http.MethodPut, "/albums/abc", `{"title":"x"}`, problem.ValidationFailed},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var p problem.Problem
+		json.NewDecoder(resp.Body).Decode(&p)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest || p.Code != tt.code {
+			t.Errorf("%s %s = %d %s, want 400 %s", tt.method, tt.path, resp.StatusCode, p.Code, tt.code)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != problem.ContentType || p.RequestID == "" || p.RequestID != resp.Header.Get("X-Request-Id") {
+			t.Errorf("%s %s = %s %+v, want a problem with the request ID of the response", tt.method, tt.path, ct, p)
+		}
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	leakcheck.Check(t)
+	repo = album.NewMemoryRepository(
+		album.Album{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699},
+		album.Album{ID: 2, Title: "Jeru", Artist: "Gerry Mulligan", Price: 1799},
+	)
+	t.Cleanup(func() { repo = nil })
+	srv := httptest.NewServer(setupRouter())
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Post(srv.URL+"/albums/", "application/json", strings.NewReader(`{"title":"Giant Steps","artist":"John Coltrane","price":"63.99"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created album.Album
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || created.ID != 3 || created.Price != 6399 {
+		t.Fatalf("POST /albums/ = %d %+v, want 201 with ID 3 and price 63.99", resp.StatusCode, created)
+	}
+
+	resp, err = http.Get(srv.URL + "/albums?limit=2&page=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list struct {
+		Data       []album.Album `json:"data"`
+		Pagination struct {
+			Total      int  `json:"total"`
+			TotalPages int  `json:"total_pages"`
+			HasNext    bool `json:"has_next"`
+		} `json:"pagination"`
+	}
+	json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	if len(list.Data) != 1 || list.Data[0].ID != 3 || list.Pagination.Total != 3 || list.Pagination.TotalPages != 2 || list.Pagination.HasNext {
+		t.Errorf("GET /albums page 2 = %+v, want album 3 of 3 on the last page", list)
+	}
+
+	for _, tt := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/albums/99", http.StatusNotFound},
+		{http.MethodDelete, "/albums/2", http.StatusOK},
+		{http.MethodDelete, "/albums/2", http.StatusNotFound},
+	} {
+		req, _ := http.NewRequest(tt.method, srv.URL+tt.path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+// failingCount is an album store whose CountByTitle fails.
+type failingCount struct {
+	*album.MemoryRepository
+}
+
+func (failingCount) CountByTitle(context.Context, string) (int, error) {
+	return 0, errors.New("count failed")
+}
+
+func TestSearchCountError(t *testing.T) {
+	leakcheck.Check(t)
+	repo = failingCount{album.NewMemoryRepository(album.Album{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699})}
+	t.Cleanup(func() { repo = nil })
+	rec := httptest.NewRecorder()
+	setupRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/albums/name/train", nil))
+	var p problem.Problem
+	json.NewDecoder(rec.Body).Decode(&p)
+	if rec.Code != http.StatusInternalServerError || p.Code != problem.Internal {
+		t.Errorf("GET /albums/name/train with a failing count = %d %s, want 500 %s", rec.Code, p.Code, problem.Internal)
+	}
+}
+
+func TestCursorPagination(t *testing.T) {
+	leakcheck.Check(t)
+	repo = album.NewMemoryRepository(
+		album.Album{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699},
+		album.Album{ID: 2, Title: "Jeru", Artist: "Gerry Mulligan", Price: 1799},
+		album.Album{ID: 4, Title: "Blue in Green", Artist: "Miles Davis", Price: 1999},
+		album.Album{ID: 7, Title: "Blue Haze", Artist: "Miles Davis", Price: 2499},
+	)
+	t.Cleanup(func() { repo = nil })
+	srv := httptest.NewServer(setupRouter())
+	t.Cleanup(srv.Close)
+
+	type page struct {
+		Data       []album.Album `json:"data"`
+		Pagination struct {
+			HasNext    bool   `json:"has_next"`
+			NextCursor string `json:"next_cursor"`
+		} `json:"pagination"`
+	}
+	walk := func(path string) []int64 {
+		var ids []int64
+		after := ""
+		for range 5 {
+			resp, err := http.Get(srv.URL + path + "&after=" + after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var p page
+			json.NewDecoder(resp.Body).Decode(&p)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("GET %s after %q = %d, want 200", path, after, resp.StatusCode)
+			}
+			for _, a := range p.Data {
+				ids = append(ids, a.ID)
+			}
+			if !p.Pagination.HasNext {
+				break
+			}
+			after = p.Pagination.NextCursor
+		}
+		return ids
+	}
+	if got := walk("/albums?limit=2"); fmt.Sprint(got) != "[1 2 4 7]" {
+		t.Errorf("walking /albums by cursor = %v, want [1 2 4 7]", got)
+	}
+	if got := walk("/albums/name/blue?limit=1"); fmt.Sprint(got) != "[1 4 7]" {
+		t.Errorf("walking /albums/name/blue by cursor = %v, want [1 4 7]", got)
+	}
+}
+
+func TestPatchAlbum(t *testing.T) {
+	leakcheck.Check(t)
+	repo = album.NewMemoryRepository(
+		album.Album{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699},
+	)
+	t.Cleanup(func() { repo = nil })
+	srv := httptest.NewServer(setupRouter())
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		path, contentType, body string
+		status                  int
+		code                    problem.Code
+	}{
+		{"/albums/1", album.MergePatchType, `{"price":"49.99"}`, http.StatusOK, ""},
+		{"/albums/1", album.JSONPatchType, `[{"op":"test","path":"/price","value":49.99},{"op":"copy","from":"/artist","path":"/title"}]`, http.StatusOK, ""},
+		{"/albums/1", album.JSONPatchType, `[{"op":"test","path":"/price","value":56.99}]`, http.StatusConflict, problem.PatchTestFailed},
+		{"/albums/1", "application/xml", `<album/>`, http.StatusUnsupportedMediaType, problem.UnsupportedMediaType},
+		{"/albums/1", album.MergePatchType, `{"artist":""}`, http.StatusBadRequest, problem.ValidationFailed},
+		{"/albums/1", album.MergePatchType, `{"created_at":"2024-01-01T00:00:00Z"}`, http.StatusBadRequest, problem.InvalidBody},
+		{"/albums/9", album.JSONPatchType, `[]`, http.StatusNotFound, problem.NotFound},
+		{"/albums/x", album.MergePatchType, `{}`, http.StatusBadRequest, problem.InvalidID},
+		{"/albums/1", album.MergePatchType, `{"title":"` + strings.Repeat("x", album.MaxPatchBytes) + `"}`, http.StatusRequestEntityTooLarge, problem.BodyTooLarge},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodPatch, srv.URL+tt.path, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", tt.contentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var p problem.Problem
+		json.NewDecoder(resp.Body).Decode(&p)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status || p.Code != tt.code {
+			t.Errorf("PATCH %s with %s = %d %s, want %d %s", tt.path, tt.body, resp.StatusCode, p.Code, tt.status, tt.code)
+		}
+	}
+	if a, _ := repo.Get(t.Context(), 1); a != (album.Album{ID: 1, Title: "John Coltrane", Artist: "John Coltrane", Price: 4999, Version: 3}) {
+		t.Errorf("album 1 = %+v, want the patched title and price only", a)
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	leakcheck.Check(t)
+	repo = album.NewMemoryRepository(
+		album.Album{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699},
+	)
+	t.Cleanup(func() { repo = nil })
+	srv := httptest.NewServer(setupRouter())
+	t.Cleanup(srv.Close)
+
+	const body = `{"title":"Giant Steps","artist":"John Coltrane","price":63.99}`
+	tests := []struct {
+		method, header, value, body string
+		status                      int
+		etag                        string
+	}{
+		{http.MethodGet, "If-None-Match", "", "", http.StatusOK, `"1"`},
+		{http.MethodGet, "If-None-Match", `W/"1"`, "", http.StatusNotModified, `"1"`},
+		{http.MethodPut, "If-Match", `"1"`, body, http.StatusOK, `"2"`},
+		{http.MethodPut, "If-Match", `"1"`, body, http.StatusPreconditionFailed, ""},
+		{http.MethodPatch, "If-Match", `"1"`, `{"price":1}`, http.StatusPreconditionFailed, ""},
+		{http.MethodPatch, "If-Match", `"2"`, `{"price":1}`, http.StatusOK, `"3"`},
+		{http.MethodDelete, "If-Match", `"2"`, "", http.StatusPreconditionFailed, ""},
+		{http.MethodGet, "If-None-Match", `"2"`, "", http.StatusOK, `"3"`},
+		{http.MethodDelete, "If-Match", `"3"`, "", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, srv.URL+"/albums/1", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(tt.header, tt.value)
+		req.Header.Set("Content-Type", album.MergePatchType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status || resp.Header.Get("ETag") != tt.etag {
+			t.Errorf("%s /albums/1 with %s: %s = %d with ETag %s, want %d with ETag %s", tt.method, tt.header, tt.value, resp.StatusCode, resp.Header.Get("ETag"), tt.status, tt.etag)
+		}
+	}
+}
+
+func TestImportExport(t *testing.T) {
+	leakcheck.Check(t)
+	repo = album.NewMemoryRepository(
+		album.Album{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699},
+	)
+	t.Cleanup(func() { repo = nil })
+	srv := httptest.NewServer(setupRouter())
+	t.Cleanup(srv.Close)
+
+	const rows = `[{"title":"Jeru","artist":"Gerry Mulligan","price":17.99},{"title":"x","artist":"y","price":0}]`
+	tests := []struct {
+		path, contentType, body string
+		status, created         int
+	}{
+		{"/albums:bulk", "application/json", rows, http.StatusUnprocessableEntity, 0},
+		{"/albums:bulk?mode=partial", "application/json", rows, http.StatusOK, 1},
+		{"/albums:bulk", "application/x-ndjson", `{"title":"Giant Steps","artist":"John Coltrane","price":"63.99"}`, http.StatusOK, 1},
+		{"/albums:bulk", "text/plain", "", http.StatusUnsupportedMediaType, 0},
+		{"/albums:bulk?mode=all", "application/json", "[]", http.StatusBadRequest, 0},
+		{"/albums:import", "application/json", "[]", http.StatusNotFound, 0},
+	}
+	for _, tt := range tests {
+		resp, err := http.Post(srv.URL+tt.path, tt.contentType, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var report bulk.Report
+		json.NewDecoder(resp.Body).Decode(&report)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status || report.Created != tt.created {
+			t.Errorf("POST %s with %s = %d %+v, want %d with %d created", tt.path, tt.contentType, resp.StatusCode, report, tt.status, tt.created)
+		}
+	}
+
+	// A body past bulk.MaxImportBytes gets a 413.
+	req := httptest.NewRequest(http.MethodPost, "/albums:bulk", strings.NewReader("["+strings.Repeat(" ", bulk.MaxImportBytes)+"]"))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	setupRouter().ServeHTTP(rec, req)
+	var p problem.Problem
+	json.NewDecoder(rec.Body).Decode(&p)
+	if rec.Code != http.StatusRequestEntityTooLarge || p.Code != problem.BodyTooLarge {
+		t.Errorf("POST /albums:bulk over %d bytes = %d %s, want 413 %s", bulk.MaxImportBytes, rec.Code, p.Code, problem.BodyTooLarge)
+	}
+
+	resp, err := http.Get(srv.URL + "/albums:export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for dec := json.NewDecoder(resp.Body); dec.More(); {
+		var a album.Album
+		if err := dec.Decode(&a); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, a.ID)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != bulk.NDJSONType || fmt.Sprint(ids) != "[1 2 3]" {
+		t.Errorf("GET /albums:export = %d %s %v, want albums 1 to 3 as NDJSON", resp.StatusCode, resp.Header.Get("Content-Type"), ids)
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"dev.mfr/album"
+	"dev.mfr/db"
+)
+
+// mysqlRepository is an album.AlbumRepository, album.Counter and
+// album.BulkCreator backed by the MySQL albums table.
+type mysqlRepository struct {
+	db *db.DB
+}
+
+var (
+	_ album.AlbumRepository = (*mysqlRepository)(nil)
+	_ album.Counter         = (*mysqlRepository)(nil)
+	_ album.BulkCreator     = (*mysqlRepository)(nil)
+)
+
+func newMySQLRepository(database *db.DB) *mysqlRepository {
+	return &mysqlRepository{db: database}
+}
+
+const (
+	selectAlbums = "SELECT id, title, artist, price, version FROM albums"
+	// fullTextMatch needs a FULLTEXT index on (title, artist).
+	fullTextMatch = "MATCH(title, artist) AGAINST(? IN NATURAL LANGUAGE MODE)"
+)
+
+// checkSchema returns an error if the albums table lacks the version column
+// that schema/001_albums_version.up.sql adds, which every query selects.
+func (r *mysqlRepository) checkSchema(ctx context.Context) error {
+	var n int
+	row := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'albums' AND column_name = 'version'")
+	if err := row.Scan(&n); err != nil {
+		return fmt.Errorf("checking the albums table: %w", err)
+	}
+	if n == 0 {
+		return errors.New(`the albums table has no version column; apply it with mysql "$DB_NAME" < schema/001_albums_version.up.sql`)
+	}
+	return nil
+}
+
+func (r *mysqlRepository) Get(ctx context.Context, id int64) (album.Album, error) {
+	var a album.Album
+	row := r.db.QueryRowContext(ctx, selectAlbums+" WHERE id = ?", id)
+	if err := row.Scan(&a.ID, &a.Title, &a.Artist, &a.Price, &a.Version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return album.Album{}, fmt.Errorf("album %d: %w", id, album.ErrNotFound)
+		}
+		return album.Album{}, err
+	}
+	return a, nil
+}
+
+func (r *mysqlRepository) List(ctx context.Context, page album.Page) ([]album.Album, error) {
+	return r.query(ctx, "", page)
+}
+
+func (r *mysqlRepository) Create(ctx context.Context, a album.Album) (album.Album, error) {
+	result, err := r.db.ExecContext(ctx, "INSERT INTO albums (title, artist, price) VALUES (?, ?, ?)", a.Title, a.Artist, a.Price)
+	if err != nil {
+		return album.Album{}, err
+	}
+	if a.ID, err = result.LastInsertId(); err != nil {
+		return album.Album{}, err
+	}
+	a.Version = 1
+	return a, nil
+}
+
+// CreateAll inserts albums with one prepared statement in a single
+// transaction.
+func (r *mysqlRepository) CreateAll(ctx context.Context, albums []album.Album) ([]album.Album, error) {
+	created := make([]album.Album, len(albums))
+	err := r.db.TransactionContext(ctx, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, "INSERT INTO albums (title, artist, price) VALUES (?, ?, ?)")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for i, a := range albums {
+			result, err := stmt.ExecContext(ctx, a.Title, a.Artist, a.Price)
+			if err != nil {
+				return fmt.Errorf("album %d of %d: %w", i+1, len(albums), err)
+			}
+			if a.ID, err = result.LastInsertId(); err != nil {
+				return err
+			}
+			a.Version = 1
+			created[i] = a
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *mysqlRepository) Update(ctx context.Context, a album.Album) (album.Album, error) {
+	result, err := r.db.ExecContext(ctx, "UPDATE albums SET title = ?, artist = ?, price = ?, version = version + 1 WHERE id = ? AND (? = 0 OR version = ?)", a.Title, a.Artist, a.Price, a.ID, a.Version, a.Version)
+	if err := r.changed(ctx, a.ID, a.Version, result, err); err != nil {
+		return album.Album{}, err
+	}
+	return r.Get(ctx, a.ID)
+}
+
+// Patch changes only the fields p sets and, since MySQL has no RETURNING,
+// reads the album back.
+func (r *mysqlRepository) Patch(ctx context.Context, id, version int64, p album.Patch) (album.Album, error) {
+	result, err := r.db.ExecContext(ctx, "UPDATE albums SET title = COALESCE(?, title), artist = COALESCE(?, artist), price = COALESCE(?, price), version = version + 1 WHERE id = ? AND (? = 0 OR version = ?)", p.Title, p.Artist, p.Price, id, version, version)
+	if err := r.changed(ctx, id, version, result, err); err != nil {
+		return album.Album{}, err
+	}
+	return r.Get(ctx, id)
+}
+
+func (r *mysqlRepository) Delete(ctx context.Context, id, version int64) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM albums WHERE id = ? AND (? = 0 OR version = ?)", id, version, version)
+	return r.changed(ctx, id, version, result, err)
+}
+
+// changed checks the result of a conditional change of the album with the
+// given ID. A change always increments the version, so no affected row means
+// the album is missing or, if it exists and version is not zero, has another
+// version.
+func (r *mysqlRepository) changed(ctx context.Context, id, version int64, result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	if version == 0 {
+		return fmt.Errorf("album %d: %w", id, album.ErrNotFound)
+	}
+	if _, err := r.Get(ctx, id); err != nil {
+		return err
+	}
+	return fmt.Errorf("album %d is not at version %d: %w", id, version, album.ErrVersionMismatch)
+}
+
+func (r *mysqlRepository) SearchByTitle(ctx context.Context, title string, page album.Page) ([]album.Album, error) {
+	return r.query(ctx, "title LIKE ?", page, "%"+title+"%")
+}
+
+func (r *mysqlRepository) SearchByArtist(ctx context.Context, artist string, page album.Page) ([]album.Album, error) {
+	return r.query(ctx, "artist LIKE ?", page, "%"+artist+"%")
+}
+
+func (r *mysqlRepository) FullText(ctx context.Context, query string, page album.Page) ([]album.Album, error) {
+	return r.query(ctx, fullTextMatch, page, query)
+}
+
+func (r *mysqlRepository) Count(ctx context.Context) (int, error) {
+	return r.count(ctx, "SELECT COUNT(*) FROM albums")
+}
+
+func (r *mysqlRepository) CountByTitle(ctx context.Context, title string) (int, error) {
+	return r.count(ctx, "SELECT COUNT(*) FROM albums WHERE title LIKE ?", "%"+title+"%")
+}
+
+func (r *mysqlRepository) CountByArtist(ctx context.Context, artist string) (int, error) {
+	return r.count(ctx, "SELECT COUNT(*) FROM albums WHERE artist LIKE ?", "%"+artist+"%")
+}
+
+func (r *mysqlRepository) CountFullText(ctx context.Context, query string) (int, error) {
+	return r.count(ctx, "SELECT COUNT(*) FROM albums WHERE "+fullTextMatch, query)
+}
+
+// query selects the albums matching where, or all of them if it is empty,
+// ordered by ID and limited to page. where holds the first placeholders of
+// args.
+func (r *mysqlRepository) query(ctx context.Context, where string, page album.Page, args ...any) ([]album.Album, error) {
+	var conds []string
+	if where != "" {
+		conds = append(conds, where)
+	}
+	if page.After > 0 {
+		conds = append(conds, "id > ?")
+		args = append(args, page.After)
+	}
+	query := selectAlbums
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY id"
+	switch {
+	case page.Limit > 0 && page.After > 0:
+		query += " LIMIT ?"
+		args = append(args, page.Limit)
+	case page.Limit > 0:
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, page.Limit, page.Offset)
+	}
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var albums []album.Album
+	for rows.Next() {
+		var a album.Album
+		if err := rows.Scan(&a.ID, &a.Title, &a.Artist, &a.Price, &a.Version); err != nil {
+			return nil, err
+		}
+		albums = append(albums, a)
+	}
+	return albums, rows.Err()
+}
+
+func (r *mysqlRepository) count(ctx context.Context, query string, args ...any) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&n)
+	return n, err
+}
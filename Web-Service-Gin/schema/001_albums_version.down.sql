@@ -0,0 +1,4 @@
+-- Undo 001_albums_version.up.sql. The service needs the version column, so
+-- only apply this after going back to a release without it:
+--   mysql "$DB_NAME" < schema/001_albums_version.down.sql
+ALTER TABLE albums DROP COLUMN version;
@@ -0,0 +1,5 @@
+-- Count the changes to every album, so clients can send its version back as
+-- an If-Match ETag and updates can refuse to overwrite a newer one. Apply it
+-- by hand before starting the service, which refuses to run without it:
+--   mysql "$DB_NAME" < schema/001_albums_version.up.sql
+ALTER TABLE albums ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
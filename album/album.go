@@ -0,0 +1,122 @@
+// Package album defines the Album shared by the album services, with its
+// validation rules and JSON and SQL encodings.
+package album
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Album is a record in the albums table.
+type Album struct {
+	ID     int64  `json:"id"`
+	Title  string `json:"title"`
+	Artist string `json:"artist"`
+	Price  Money  `json:"price"`
+	// CreatedAt is zero when the backend does not track it.
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	// Version counts the changes to the album, starting at 1. It is sent
+	// as the ETag of the album rather than in its JSON.
+	Version int64 `json:"-"`
+}
+
+// Limits enforced by Validate. They match the albums table columns
+// VARCHAR(255) and DECIMAL(10, 2).
+const (
+	MaxTitleLength  = 255
+	MaxArtistLength = 255
+	MaxPrice        = Money(99999999_99)
+)
+
+// Reasons a field fails validation, wrapped in a *FieldError. Check for them
+// with errors.Is.
+var (
+	ErrRequired    = errors.New("is required")
+	ErrTooLong     = errors.New("is too long")
+	ErrNotPositive = errors.New("must be positive")
+	ErrTooLarge    = errors.New("is too large")
+)
+
+// FieldError records why one field of an album is invalid.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + " " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError lists every invalid field of an album.
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "invalid album: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap lets errors.Is and errors.As look at the individual fields.
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Validate checks every field a client sets and returns a *ValidationError
+// listing all problems, or nil. The ID and CreatedAt are not checked.
+func (a Album) Validate() error {
+	return validate(&a.Title, &a.Artist, &a.Price)
+}
+
+// validate checks the fields that are not nil.
+func validate(title, artist *string, price *Money) error {
+	var failed []*FieldError
+	check := func(field string, err error) {
+		if err != nil {
+			failed = append(failed, &FieldError{Field: field, Err: err})
+		}
+	}
+	if title != nil {
+		check("title", text(*title, MaxTitleLength))
+	}
+	if artist != nil {
+		check("artist", text(*artist, MaxArtistLength))
+	}
+	if price != nil {
+		switch {
+		case *price <= 0:
+			check("price", ErrNotPositive)
+		case *price > MaxPrice:
+			check("price", fmt.Errorf("%w (maximum %s)", ErrTooLarge, MaxPrice))
+		}
+	}
+	if len(failed) > 0 {
+		return &ValidationError{Errors: failed}
+	}
+	return nil
+}
+
+func text(s string, maxLength int) error {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		return ErrRequired
+	case utf8.RuneCountInString(s) > maxLength:
+		return fmt.Errorf("%w (maximum %d characters)", ErrTooLong, maxLength)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package album
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+	}{
+		{"12", 1200},
+		{"12.5", 1250},
+		{"12.05", 1205},
+		{"0.99", 99},
+		{".5", 50},
+		{"-3.10", -310},
+		{"+7.00", 700},
+		{" 19.99 ", 1999},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", ".", "-", "1.234", "1e3", "abc", "1.2.3", "--1", "12345678901234567"} {
+		if _, err := ParseMoney(in); !errors.Is(err, ErrInvalidMoney) {
+			t.Errorf("ParseMoney(%q) error = %v, want ErrInvalidMoney", in, err)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	for m, want := range map[Money]string{0: "0.00", 5: "0.05", 1999: "19.99", -99: "-0.99", 100000: "1000.00"} {
+		if got := m.String(); got != want {
+			t.Errorf("Money(%d).String() = %q, want %q", int64(m), got, want)
+		}
+	}
+}
+
+func TestFromFloat(t *testing.T) {
+	// 9.99 has no exact float representation.
+	if got := FromFloat(float64(float32(9.99))); got != 999 {
+		t.Errorf("FromFloat(float32 9.99) = %d, want 999", got)
+	}
+	if got := FromFloat(0.1 + 0.2); got != 30 {
+		t.Errorf("FromFloat(0.1 + 0.2) = %d, want 30", got)
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(Album{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"id":1,"title":"Blue Train","artist":"John Coltrane","price":56.99}`; string(data) != want {
+		t.Errorf("Marshal = %s, want %s", data, want)
+	}
+
+	for _, in := range []string{`{"price":56.99}`, `{"price":"56.99"}`} {
+		var a Album
+		if err := json.Unmarshal([]byte(in), &a); err != nil || a.Price != 5699 {
+			t.Errorf("Unmarshal(%s) = %d, %v, want 5699", in, a.Price, err)
+		}
+	}
+	var a Album
+	if err := json.Unmarshal([]byte(`{"price":56.999}`), &a); !errors.Is(err, ErrInvalidMoney) {
+		t.Errorf("Unmarshal(56.999) error = %v, want ErrInvalidMoney", err)
+	}
+}
+
+func TestMoneySQL(t *testing.T) {
+	tests := []struct {
+		src  any
+		want Money
+	}{
+		{[]byte("17.99"), 1799},
+		{"39.90", 3990},
+		{int64(12), 1200},
+		{float64(9.99), 999},
+		{float32(9.99), 999},
+	}
+	for _, tt := range tests {
+		var m Money
+		if err := m.Scan(tt.src); err != nil || m != tt.want {
+			t.Errorf("Scan(%#v) = %d, %v, want %d", tt.src, m, err, tt.want)
+		}
+	}
+	var m Money
+	if err := m.Scan(nil); err == nil {
+		t.Error("Scan(nil) = nil error, want an error")
+	}
+	if v, err := Money(1799).Value(); err != nil || v != "17.99" {
+		t.Errorf("Value() = %v, %v, want \"17.99\"", v, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := Album{Title: "Giant Steps", Artist: "John Coltrane", Price: 6399}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate(%+v) = %v, want nil", valid, err)
+	}
+
+	err := Album{Title: "  ", Artist: strings.Repeat("a", MaxArtistLength+1), Price: 0}.Validate()
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Errors) != 3 {
+		t.Fatalf("Validate error = %v, want a *ValidationError with 3 fields", err)
+	}
+	for _, target := range []error{ErrRequired, ErrTooLong, ErrNotPositive} {
+		if !errors.Is(err, target) {
+			t.Errorf("Validate error = %v, want it to wrap %v", err, target)
+		}
+	}
+	if verr.Errors[0].Field != "title" {
+		t.Errorf("first invalid field = %q, want \"title\"", verr.Errors[0].Field)
+	}
+
+	if err := (Album{Title: "x", Artist: "y", Price: MaxPrice + 1}).Validate(); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Validate(price above maximum) = %v, want ErrTooLarge", err)
+	}
+}
@@ -0,0 +1,256 @@
+// Package bulk reads and writes whole catalogs of albums, for the import and
+// export endpoints of the album services. Imports are JSON arrays, NDJSON or
+// CSV; exports are NDJSON or CSV.
+package bulk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"dev.mfr/album"
+)
+
+// Media types of the formats. Imports also accept application/ndjson.
+const (
+	JSONType   = "application/json"
+	NDJSONType = "application/x-ndjson"
+	CSVType    = "text/csv"
+)
+
+var (
+	// ErrUnsupportedType is returned by NewDecoder for a media type it does
+	// not know, and by NewEncoder for an unknown format.
+	ErrUnsupportedType = errors.New("unsupported bulk format")
+	// ErrMalformed is returned, wrapped with details, for input that cannot
+	// be read any further, such as a JSON array with a syntax error or a CSV
+	// file without the required columns.
+	ErrMalformed = errors.New("malformed bulk input")
+	// ErrInvalidRow is returned, wrapped with details, for one row that
+	// cannot be read as an album. The rows after it can still be read.
+	ErrInvalidRow = errors.New("invalid row")
+)
+
+// A Decoder reads the albums of an import one row at a time.
+type Decoder struct {
+	next func() (album.Album, error)
+}
+
+// NewDecoder returns a Decoder for r, whose format is given by its
+// Content-Type. A CSV file starts with a header naming its title, artist and
+// price columns, in any order; an id column, as written by an export, is
+// ignored.
+func NewDecoder(contentType string, r io.Reader) (*Decoder, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case JSONType:
+		return &Decoder{next: jsonArray(json.NewDecoder(r))}, nil
+	case NDJSONType, "application/ndjson":
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, 1<<20)
+		return &Decoder{next: ndjson(scanner)}, nil
+	case CSVType:
+		return &Decoder{next: csvRows(csv.NewReader(r))}, nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnsupportedType, mediaType)
+}
+
+// Next returns the album of the next row, or io.EOF after the last one. An
+// error wrapping ErrInvalidRow is about that row only, and Next may be called
+// again; any other error ends the input.
+func (d *Decoder) Next() (album.Album, error) {
+	return d.next()
+}
+
+func jsonArray(dec *json.Decoder) func() (album.Album, error) {
+	started := false
+	return func() (album.Album, error) {
+		if !started {
+			tok, err := dec.Token()
+			if err != nil {
+				return album.Album{}, fmt.Errorf("%w: %w", ErrMalformed, err)
+			}
+			if tok != json.Delim('[') {
+				return album.Album{}, fmt.Errorf("%w: a JSON import must be an array of albums", ErrMalformed)
+			}
+			started = true
+		}
+		if !dec.More() {
+			if _, err := dec.Token(); err != nil {
+				return album.Album{}, fmt.Errorf("%w: %w", ErrMalformed, err)
+			}
+			return album.Album{}, io.EOF
+		}
+		var a album.Album
+		if err := dec.Decode(&a); err != nil {
+			// The decoder skips past a value of the wrong type, but not past a
+			// syntax error.
+			var syntax *json.SyntaxError
+			if errors.As(err, &syntax) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return album.Album{}, fmt.Errorf("%w: %w", ErrMalformed, err)
+			}
+			return album.Album{}, fmt.Errorf("%w: %w", ErrInvalidRow, err)
+		}
+		return a, nil
+	}
+}
+
+func ndjson(scanner *bufio.Scanner) func() (album.Album, error) {
+	return func() (album.Album, error) {
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			var a album.Album
+			if err := json.Unmarshal(line, &a); err != nil {
+				return album.Album{}, fmt.Errorf("%w: %w", ErrInvalidRow, err)
+			}
+			return a, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return album.Album{}, fmt.Errorf("%w: %w", ErrMalformed, err)
+		}
+		return album.Album{}, io.EOF
+	}
+}
+
+// csvColumns are the columns of an exported CSV file, of which an import
+// needs all but id.
+var csvColumns = []string{"id", "title", "artist", "price"}
+
+func csvRows(r *csv.Reader) func() (album.Album, error) {
+	var index map[string]int
+	return func() (album.Album, error) {
+		if index == nil {
+			header, err := r.Read()
+			if err != nil {
+				return album.Album{}, fmt.Errorf("%w: reading the CSV header: %w", ErrMalformed, err)
+			}
+			if index, err = csvHeader(header); err != nil {
+				return album.Album{}, err
+			}
+		}
+		record, err := r.Read()
+		if err == io.EOF {
+			return album.Album{}, io.EOF
+		}
+		if err != nil {
+			var parse *csv.ParseError
+			if errors.As(err, &parse) {
+				return album.Album{}, fmt.Errorf("%w: %w", ErrInvalidRow, err)
+			}
+			return album.Album{}, fmt.Errorf("%w: %w", ErrMalformed, err)
+		}
+		a := album.Album{Title: record[index["title"]], Artist: record[index["artist"]]}
+		if a.Price, err = album.ParseMoney(strings.TrimSpace(record[index["price"]])); err != nil {
+			return album.Album{}, fmt.Errorf("%w: price: %w", ErrInvalidRow, err)
+		}
+		return a, nil
+	}
+}
+
+// csvHeader maps the column names of header to their positions.
+func csvHeader(header []string) (map[string]int, error) {
+	index := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(csvColumns, name) {
+			return nil, fmt.Errorf("%w: unknown CSV column %q", ErrMalformed, name)
+		}
+		index[name] = i
+	}
+	for _, name := range csvColumns[1:] {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("%w: the CSV header has no %s column", ErrMalformed, name)
+		}
+	}
+	return index, nil
+}
+
+// An Encoder writes albums in the format of an export.
+type Encoder struct {
+	contentType string
+	encode      func(album.Album) error
+	flush       func() error
+}
+
+// NewEncoder returns an Encoder writing to w in format, "ndjson" or "csv".
+// A CSV export has the columns id, title, artist and price, and a header
+// naming them.
+func NewEncoder(format string, w io.Writer) (*Encoder, error) {
+	switch format {
+	case "ndjson":
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+		return &Encoder{contentType: NDJSONType, encode: func(a album.Album) error { return enc.Encode(a) }, flush: flushTo(w, bw.Flush)}, nil
+	case "csv":
+		cw := csv.NewWriter(w)
+		wroteHeader := false
+		header := func() error {
+			if wroteHeader {
+				return nil
+			}
+			wroteHeader = true
+			return cw.Write(csvColumns)
+		}
+		encode := func(a album.Album) error {
+			if err := header(); err != nil {
+				return err
+			}
+			return cw.Write([]string{strconv.FormatInt(a.ID, 10), a.Title, a.Artist, a.Price.String()})
+		}
+		// An empty export still has its header.
+		flush := func() error {
+			if err := header(); err != nil {
+				return err
+			}
+			cw.Flush()
+			return cw.Error()
+		}
+		return &Encoder{contentType: CSVType, encode: encode, flush: flushTo(w, flush)}, nil
+	}
+	return nil, fmt.Errorf("%w %q, want ndjson or csv", ErrUnsupportedType, format)
+}
+
+// flushTo returns flush followed, when w is an http.Flusher such as a
+// ResponseWriter, by flushing w, so what was written reaches the client
+// instead of waiting in the server's buffer.
+func flushTo(w io.Writer, flush func() error) func() error {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		return flush
+	}
+	return func() error {
+		if err := flush(); err != nil {
+			return err
+		}
+		f.Flush()
+		return nil
+	}
+}
+
+// ContentType returns the media type of what e writes.
+func (e *Encoder) ContentType() string {
+	return e.contentType
+}
+
+// Encode writes a. It may be buffered until Flush.
+func (e *Encoder) Encode(a album.Album) error {
+	return e.encode(a)
+}
+
+// Flush writes any buffered albums, and flushes the writer of e if it is an
+// http.Flusher.
+func (e *Encoder) Flush() error {
+	return e.flush()
+}
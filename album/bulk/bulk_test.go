@@ -0,0 +1,207 @@
+package bulk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dev.mfr/album"
+)
+
+// decodeAll returns the titles of the albums dec reads, with "!" for an
+// invalid row.
+func decodeAll(t *testing.T, dec *Decoder) ([]string, error) {
+	t.Helper()
+	var titles []string
+	for {
+		a, err := dec.Next()
+		switch {
+		case err == io.EOF:
+			return titles, nil
+		case errors.Is(err, ErrInvalidRow):
+			titles = append(titles, "!")
+		case err != nil:
+			return titles, err
+		default:
+			titles = append(titles, a.Title)
+		}
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	tests := []struct {
+		contentType, body string
+		want              string
+	}{
+		{JSONType, `[{"title":"Jeru","price":1}, {"title":2}, {"title":"Blue Train"}]`, "[Jeru ! Blue Train]"},
+		{JSONType, `[]`, "[]"},
+		{NDJSONType, "{\"title\":\"Jeru\"}\n\nnot json\n{\"title\":\"Blue Train\"}", "[Jeru ! Blue Train]"},
+		{"text/csv; charset=utf-8", "Price,Title,Artist\n17.99,Jeru,Gerry Mulligan\nabc,x,y\n1,\"Blue, Train\",John Coltrane\n1,2\n", "[Jeru ! Blue, Train !]"},
+		{CSVType, "id,title,artist,price\n7,Jeru,Gerry Mulligan,17.99\n", "[Jeru]"},
+	}
+	for _, tt := range tests {
+		dec, err := NewDecoder(tt.contentType, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := decodeAll(t, dec)
+		if err != nil || fmt.Sprint(got) != tt.want {
+			t.Errorf("decoding %s %q = %v, %v, want %s", tt.contentType, tt.body, got, err, tt.want)
+		}
+	}
+
+	for contentType, body := range map[string]string{
+		JSONType:               `{"title":"Jeru"}`,
+		JSONType + " ":         `[{"title":"Jeru"}, {`,
+		CSVType:                "title,artist\nJeru,Gerry Mulligan\n",
+		CSVType + "; x=y":      "title,artist,price,genre\n",
+		"text/csv; charset=x ": "",
+	} {
+		dec, err := NewDecoder(contentType, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := decodeAll(t, dec); !errors.Is(err, ErrMalformed) {
+			t.Errorf("decoding %s %q = %v, want ErrMalformed", contentType, body, err)
+		}
+	}
+	if _, err := NewDecoder("application/xml", nil); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("NewDecoder(application/xml) = %v, want ErrUnsupportedType", err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	const body = `[
+		{"title":"Jeru","artist":"Gerry Mulligan","price":17.99},
+		{"title":"","artist":"Nobody","price":1},
+		{"title":"Blue Train","artist":"John Coltrane","price":"abc"},
+		{"title":"Giant Steps","artist":"John Coltrane","price":63.99}
+	]`
+	ctx := context.Background()
+	for _, tt := range []struct {
+		mode     Mode
+		statuses string
+		status   int
+	}{
+		{Atomic, "[skipped invalid invalid skipped]", http.StatusUnprocessableEntity},
+		{Partial, "[created invalid invalid created]", http.StatusOK},
+	} {
+		store := album.NewMemoryRepository()
+		dec, _ := NewDecoder(JSONType, strings.NewReader(body))
+		report, err := Import(ctx, dec, tt.mode, store)
+		if err != nil {
+			t.Fatalf("Import(%s) = %v", tt.mode, err)
+		}
+		var statuses []Status
+		for _, row := range report.Rows {
+			statuses = append(statuses, row.Status)
+		}
+		if fmt.Sprint(statuses) != tt.statuses || report.Total != 4 || report.Invalid != 2 || report.StatusCode() != tt.status {
+			t.Errorf("Import(%s) = %+v, want rows %s and status %d", tt.mode, report, tt.statuses, tt.status)
+		}
+		if n, _ := store.Count(ctx); n != report.Created {
+			t.Errorf("Import(%s) stored %d albums, reported %d", tt.mode, n, report.Created)
+		}
+	}
+
+	dec, _ := NewDecoder(JSONType, strings.NewReader(body))
+	report, _ := Import(ctx, dec, Partial, album.NewMemoryRepository())
+	if row := report.Rows[1]; len(row.Errors) != 1 || row.Errors[0].Field != "title" || row.Errors[0].Code != "required" {
+		t.Errorf("row 2 = %+v, want a required title", row)
+	}
+	if row := report.Rows[3]; row.ID != 2 {
+		t.Errorf("row 4 = %+v, want ID 2", row)
+	}
+
+	dec, _ = NewDecoder(JSONType, strings.NewReader(`[{"title":"Jeru","artist":"Gerry Mulligan","price":1}, {`))
+	store := album.NewMemoryRepository()
+	if _, err := Import(ctx, dec, Partial, store); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Import(truncated array) = %v, want ErrMalformed", err)
+	}
+	if n, _ := store.Count(ctx); n != 0 {
+		t.Errorf("Import(truncated array) stored %d albums, want none", n)
+	}
+
+	// An import cut off by http.MaxBytesReader fails whatever its format.
+	csvBody := "title,artist,price\n" + strings.Repeat("Jeru,Gerry Mulligan,17.99\n", 4)
+	ndjsonBody := strings.Repeat(`{"title":"Jeru","artist":"Gerry Mulligan","price":17.99}`+"\n", 4)
+	for _, input := range []struct{ contentType, body string }{{JSONType, body}, {NDJSONType, ndjsonBody}, {CSVType, csvBody}} {
+		dec, _ := NewDecoder(input.contentType, http.MaxBytesReader(nil, io.NopCloser(strings.NewReader(input.body)), 64))
+		if _, err := Import(ctx, dec, Partial, store); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("Import(%s over the limit) = %v, want ErrTooLarge", input.contentType, err)
+		}
+	}
+	if n, _ := store.Count(ctx); n != 0 {
+		t.Errorf("Import(over the limit) stored %d albums, want none", n)
+	}
+
+	// Even when the limit is reached before the opening bracket.
+	padded := strings.Repeat(" ", 128) + body
+	dec, _ = NewDecoder(JSONType, http.MaxBytesReader(nil, io.NopCloser(strings.NewReader(padded)), 64))
+	if _, err := Import(ctx, dec, Partial, store); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Import(JSON over the limit before the array) = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	albums := make([]album.Album, ExportBatch+2)
+	for i := range albums {
+		albums[i] = album.Album{ID: int64(i + 1), Title: fmt.Sprintf("Album %d", i+1), Artist: "Miles Davis", Price: 1999}
+	}
+	store := album.NewMemoryRepository(albums...)
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	enc, _ := NewEncoder("csv", &buf)
+	n, err := Export(ctx, enc, store.List)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if err != nil || n != len(albums) || len(lines) != len(albums)+1 || lines[0] != "id,title,artist,price" || lines[1] != "1,Album 1,Miles Davis,19.99" {
+		t.Errorf("Export(csv) = %d, %v with %d lines starting %q, want a header and %d albums", n, err, len(lines), lines[:2], len(albums))
+	}
+
+	// An export can be imported again.
+	dec, _ := NewDecoder(enc.ContentType(), &buf)
+	if report, err := Import(ctx, dec, Atomic, album.NewMemoryRepository()); err != nil || report.Created != len(albums) {
+		t.Errorf("importing the CSV export = %d created, %v, want %d", report.Created, err, len(albums))
+	}
+
+	// Every batch is flushed through to an http.ResponseWriter.
+	for _, format := range []string{"csv", "ndjson"} {
+		w := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+		enc, _ := NewEncoder(format, w)
+		if _, err := Export(ctx, enc, store.List); err != nil || w.flushes != 2 || w.Body.Len() == 0 {
+			t.Errorf("Export(%s) flushed the ResponseWriter %d times, %v, want 2 times", format, w.flushes, err)
+		}
+	}
+
+	buf.Reset()
+	enc, _ = NewEncoder("ndjson", &buf)
+	if n, err := Export(ctx, enc, album.NewMemoryRepository().List); n != 0 || err != nil || buf.Len() != 0 {
+		t.Errorf("Export(ndjson, no albums) = %d, %v, %q, want nothing", n, err, buf.String())
+	}
+	errList := errors.New("list failed")
+	n, err = Export(ctx, enc, func(context.Context, album.Page) ([]album.Album, error) { return nil, errList })
+	if n != 0 || !errors.Is(err, errList) {
+		t.Errorf("Export(failing list) = %d, %v, want 0, %v", n, err, errList)
+	}
+	if _, err := NewEncoder("xml", &buf); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("NewEncoder(xml) = %v, want ErrUnsupportedType", err)
+	}
+}
+
+// flushRecorder counts the calls to its Flush.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushes++
+	r.ResponseRecorder.Flush()
+}
@@ -0,0 +1,179 @@
+package bulk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"dev.mfr/album"
+	"dev.mfr/album/problem"
+)
+
+// Mode says what an import does when some of its rows are invalid.
+type Mode string
+
+const (
+	// Atomic stores every row or, if any row is invalid, none.
+	Atomic Mode = "atomic"
+	// Partial stores the valid rows and reports the invalid ones.
+	Partial Mode = "partial"
+)
+
+var (
+	// ErrInvalidMode is returned by ParseMode for an unknown mode.
+	ErrInvalidMode = errors.New("invalid import mode")
+	// ErrTooLarge is returned, wrapped with details, by Import when its
+	// input was cut off by an http.MaxBytesReader.
+	ErrTooLarge = errors.New("import too large")
+)
+
+// MaxImportBytes is the largest request body the import endpoints read,
+// through an http.MaxBytesReader, so one upload cannot exhaust the memory
+// Import holds the valid rows in.
+const MaxImportBytes = 32 << 20
+
+// ParseMode parses the mode parameter of an import. The empty string is
+// Atomic.
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "", Atomic:
+		return Atomic, nil
+	case Partial:
+		return Partial, nil
+	}
+	return "", fmt.Errorf("%w %q, want atomic or partial", ErrInvalidMode, s)
+}
+
+// Status is what an import did with one row.
+type Status string
+
+const (
+	Created Status = "created"
+	Invalid Status = "invalid"
+	// Skipped is a valid row of an Atomic import that was not stored
+	// because other rows are invalid.
+	Skipped Status = "skipped"
+)
+
+// Row reports on one row of an import.
+type Row struct {
+	// Row counts the albums of the input from 1, leaving out the header of
+	// a CSV file and blank NDJSON lines.
+	Row    int    `json:"row"`
+	Status Status `json:"status"`
+	// ID is the ID of a created album.
+	ID     int64  `json:"id,omitempty"`
+	Detail string `json:"detail,omitempty"`
+	// Errors lists the invalid fields of an album that failed validation.
+	Errors []problem.FieldError `json:"errors,omitempty"`
+}
+
+// Report is the result of an import, with a Row for every row of its input.
+type Report struct {
+	Mode    Mode  `json:"mode"`
+	Total   int   `json:"total"`
+	Created int   `json:"created"`
+	Invalid int   `json:"invalid"`
+	Rows    []Row `json:"rows"`
+}
+
+// StatusCode returns the HTTP status of a response carrying r: 422 if
+// invalid rows kept an Atomic import from storing anything, or else 200.
+func (r Report) StatusCode() int {
+	if r.Mode == Atomic && r.Invalid > 0 {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusOK
+}
+
+// Import reads and validates every row of dec, then stores the valid albums
+// with a single call to store.CreateAll, in one transaction. An Atomic import
+// stores nothing if any row is invalid.
+//
+// Import returns an error wrapping ErrTooLarge for input over the limit of
+// an http.MaxBytesReader, ErrMalformed for other input it cannot read to the
+// end, and the error of store if storing fails; either way nothing is stored.
+func Import(ctx context.Context, dec *Decoder, mode Mode, store album.BulkCreator) (Report, error) {
+	report := Report{Mode: mode}
+	var albums []album.Album
+	var rows []int // the index in report.Rows of each of albums
+	for {
+		a, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		row := Row{Row: len(report.Rows) + 1}
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return Report{}, fmt.Errorf("%w: the body is over %d bytes", ErrTooLarge, tooLarge.Limit)
+		}
+		switch {
+		case errors.Is(err, ErrInvalidRow):
+			row.Status, row.Detail = Invalid, err.Error()
+		case err != nil:
+			return Report{}, fmt.Errorf("row %d: %w", row.Row, err)
+		default:
+			if err := a.Validate(); err != nil {
+				row.Status, row.Detail = Invalid, err.Error()
+				row.Errors = problem.Validation(err).Errors
+				break
+			}
+			row.Status = Skipped
+			albums = append(albums, a)
+			rows = append(rows, len(report.Rows))
+		}
+		report.Rows = append(report.Rows, row)
+	}
+	report.Total = len(report.Rows)
+	report.Invalid = report.Total - len(albums)
+	if len(albums) == 0 || (mode == Atomic && report.Invalid > 0) {
+		return report, nil
+	}
+
+	created, err := store.CreateAll(ctx, albums)
+	if err != nil {
+		return Report{}, err
+	}
+	for i, a := range created {
+		row := &report.Rows[rows[i]]
+		row.Status, row.ID = Created, a.ID
+	}
+	report.Created = len(created)
+	return report, nil
+}
+
+// ExportBatch is how many albums Export lists at a time.
+const ExportBatch = 500
+
+// Export writes every album list returns to enc. It lists ExportBatch albums
+// at a time, paging by cursor, and flushes enc after each batch, which also
+// flushes an http.ResponseWriter, so the catalog is streamed to the client
+// rather than held in memory.
+//
+// Export returns how many albums it flushed. After an error from list, zero
+// means nothing was written, so the error can still be sent to the client.
+func Export(ctx context.Context, enc *Encoder, list func(context.Context, album.Page) ([]album.Album, error)) (int, error) {
+	n := 0
+	page := album.Page{Limit: ExportBatch}
+	for {
+		albums, err := list(ctx, page)
+		if err != nil {
+			return n, err
+		}
+		for _, a := range albums {
+			if err := enc.Encode(a); err != nil {
+				return n, err
+			}
+		}
+		if err := enc.Flush(); err != nil {
+			return n, err
+		}
+		n += len(albums)
+		if len(albums) < page.Limit {
+			return n, nil
+		}
+		page.After = albums[len(albums)-1].ID
+	}
+}
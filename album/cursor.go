@@ -0,0 +1,47 @@
+package album
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidCursor is returned by ParseCursor for a token it did not issue.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks where a keyset-paginated listing stopped: the next page
+// holds the albums after ID. Unlike an offset, it neither skips nor repeats
+// albums when others are added or removed between pages.
+type Cursor struct {
+	ID int64 `json:"id"`
+}
+
+// String returns the cursor as an opaque token for the after query
+// parameter.
+func (c Cursor) String() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseCursor decodes a token returned by Cursor.String.
+func ParseCursor(token string) (Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID <= 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// NextCursor trims albums, listed with a Limit of limit+1, to limit albums
+// and returns the token for the page after them, or "" if none follows.
+func NextCursor(albums []Album, limit int) ([]Album, string) {
+	if limit <= 0 || len(albums) <= limit {
+		return albums, ""
+	}
+	albums = albums[:limit]
+	return albums, Cursor{ID: albums[limit-1].ID}.String()
+}
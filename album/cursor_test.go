@@ -0,0 +1,32 @@
+package album
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCursor(t *testing.T) {
+	token := Cursor{ID: 42}.String()
+	if c, err := ParseCursor(token); err != nil || c.ID != 42 {
+		t.Errorf("ParseCursor(%q) = %+v, %v, want ID 42", token, c, err)
+	}
+	for _, token := range []string{"", "42", "not base64!", Cursor{}.String(), Cursor{ID: -1}.String()} {
+		if _, err := ParseCursor(token); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("ParseCursor(%q) = %v, want ErrInvalidCursor", token, err)
+		}
+	}
+}
+
+func TestNextCursor(t *testing.T) {
+	albums := []Album{{ID: 2}, {ID: 5}, {ID: 9}}
+	page, next := NextCursor(albums, 2)
+	if got := ids(page); len(got) != 2 || got[1] != 5 {
+		t.Errorf("NextCursor(3 albums, 2) page = %v, want [2 5]", got)
+	}
+	if c, err := ParseCursor(next); err != nil || c.ID != 5 {
+		t.Errorf("NextCursor(3 albums, 2) cursor = %q (%+v, %v), want ID 5", next, c, err)
+	}
+	if page, next := NextCursor(albums, 3); len(page) != 3 || next != "" {
+		t.Errorf("NextCursor(3 albums, 3) = %v, %q, want all albums and no cursor", ids(page), next)
+	}
+}
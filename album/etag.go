@@ -0,0 +1,60 @@
+package album
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ETag returns the strong entity tag of a, which changes with its Version.
+func (a Album) ETag() string {
+	return `"` + strconv.FormatInt(a.Version, 10) + `"`
+}
+
+// IfMatch reports whether an If-Match header, a list of entity tags or "*",
+// holds for a. Only a strong tag equal to the ETag of a matches; an empty
+// header always holds.
+func IfMatch(header string, a Album) bool {
+	if strings.TrimSpace(header) == "" {
+		return true
+	}
+	etag := a.ETag()
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// IfNoneMatch reports whether an If-None-Match header holds for a, that is
+// whether none of its entity tags matches the ETag of a. Weak tags match
+// too. When it does not hold, a GET should be answered with 304 Not Modified.
+func IfNoneMatch(header string, a Album) bool {
+	etag := a.ETag()
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return false
+		}
+	}
+	return true
+}
+
+// Precondition returns the version a change must be conditional on under an
+// If-Match header: 0 without one, or the Version of the album current
+// returns when the header matches its ETag. It returns the error of current,
+// or one wrapping ErrVersionMismatch when the header does not match.
+func Precondition(header string, current func() (Album, error)) (int64, error) {
+	if strings.TrimSpace(header) == "" {
+		return 0, nil
+	}
+	a, err := current()
+	if err != nil {
+		return 0, err
+	}
+	if !IfMatch(header, a) {
+		return 0, fmt.Errorf("If-Match %s does not match ETag %s: %w", header, a.ETag(), ErrVersionMismatch)
+	}
+	return a.Version, nil
+}
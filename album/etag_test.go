@@ -0,0 +1,57 @@
+package album
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConditions(t *testing.T) {
+	a := Album{ID: 1, Version: 3}
+	if got := a.ETag(); got != `"3"` {
+		t.Fatalf("ETag = %s, want \"3\"", got)
+	}
+	tests := []struct {
+		header               string
+		ifMatch, ifNoneMatch bool
+	}{
+		{``, true, true},
+		{`"3"`, true, false},
+		{`"2", "3"`, true, false},
+		{`*`, true, false},
+		{`"2"`, false, true},
+		{`W/"3"`, false, false},
+		{`3`, false, true},
+	}
+	for _, tt := range tests {
+		if got := IfMatch(tt.header, a); got != tt.ifMatch {
+			t.Errorf("IfMatch(%s) = %v, want %v", tt.header, got, tt.ifMatch)
+		}
+		if got := IfNoneMatch(tt.header, a); got != tt.ifNoneMatch {
+			t.Errorf("IfNoneMatch(%s) = %v, want %v", tt.header, got, tt.ifNoneMatch)
+		}
+	}
+}
+
+func TestPrecondition(t *testing.T) {
+	a := Album{ID: 1, Version: 3}
+	current := func() (Album, error) { return a, nil }
+	tests := []struct {
+		header  string
+		version int64
+		err     error
+	}{
+		{``, 0, nil},
+		{`"3"`, 3, nil},
+		{`*`, 3, nil},
+		{`"2"`, 0, ErrVersionMismatch},
+	}
+	for _, tt := range tests {
+		if version, err := Precondition(tt.header, current); version != tt.version || !errors.Is(err, tt.err) {
+			t.Errorf("Precondition(%s) = %d, %v, want %d, %v", tt.header, version, err, tt.version, tt.err)
+		}
+	}
+	gone := func() (Album, error) { return Album{}, ErrNotFound }
+	if _, err := Precondition(`"3"`, gone); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Precondition(missing album) = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,3 @@
+module dev.mfr/album
+
+go 1.24.5
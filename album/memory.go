@@ -0,0 +1,206 @@
+package album
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	_ AlbumRepository = (*MemoryRepository)(nil)
+	_ Counter         = (*MemoryRepository)(nil)
+	_ BulkCreator     = (*MemoryRepository)(nil)
+)
+
+// MemoryRepository is an AlbumRepository, Counter and BulkCreator that
+// keeps albums in memory, for tests and for running a service without a
+// database. It is safe for concurrent use.
+type MemoryRepository struct {
+	mu     sync.RWMutex
+	albums map[int64]Album
+	nextID int64
+}
+
+// NewMemoryRepository returns a repository holding albums, which keep
+// their IDs; new albums get IDs above the largest one.
+func NewMemoryRepository(albums ...Album) *MemoryRepository {
+	r := &MemoryRepository{albums: make(map[int64]Album)}
+	for _, a := range albums {
+		a.Version = max(a.Version, 1)
+		r.albums[a.ID] = a
+		r.nextID = max(r.nextID, a.ID)
+	}
+	return r
+}
+
+func (r *MemoryRepository) Get(_ context.Context, id int64) (Album, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	a, ok := r.albums[id]
+	if !ok {
+		return Album{}, fmt.Errorf("album %d: %w", id, ErrNotFound)
+	}
+	return a, nil
+}
+
+func (r *MemoryRepository) List(_ context.Context, page Page) ([]Album, error) {
+	return paginate(r.match(func(Album) bool { return true }), page), nil
+}
+
+func (r *MemoryRepository) Create(_ context.Context, a Album) (Album, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	a.ID = r.nextID
+	a.Version = 1
+	r.albums[a.ID] = a
+	return a, nil
+}
+
+func (r *MemoryRepository) CreateAll(_ context.Context, albums []Album) ([]Album, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	created := make([]Album, len(albums))
+	for i, a := range albums {
+		r.nextID++
+		a.ID = r.nextID
+		a.Version = 1
+		r.albums[a.ID] = a
+		created[i] = a
+	}
+	return created, nil
+}
+
+func (r *MemoryRepository) Update(_ context.Context, a Album) (Album, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old, err := r.current(a.ID, a.Version)
+	if err != nil {
+		return Album{}, err
+	}
+	a.CreatedAt = old.CreatedAt
+	a.Version = old.Version + 1
+	r.albums[a.ID] = a
+	return a, nil
+}
+
+func (r *MemoryRepository) Patch(_ context.Context, id, version int64, p Patch) (Album, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, err := r.current(id, version)
+	if err != nil {
+		return Album{}, err
+	}
+	a = p.Apply(a)
+	a.Version++
+	r.albums[id] = a
+	return a, nil
+}
+
+func (r *MemoryRepository) Delete(_ context.Context, id, version int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.current(id, version); err != nil {
+		return err
+	}
+	delete(r.albums, id)
+	return nil
+}
+
+// current returns the album with the given ID, if it has version or version
+// is zero. r.mu must be held.
+func (r *MemoryRepository) current(id, version int64) (Album, error) {
+	a, ok := r.albums[id]
+	if !ok {
+		return Album{}, fmt.Errorf("album %d: %w", id, ErrNotFound)
+	}
+	if version != 0 && a.Version != version {
+		return Album{}, fmt.Errorf("album %d is at version %d, not %d: %w", id, a.Version, version, ErrVersionMismatch)
+	}
+	return a, nil
+}
+
+func (r *MemoryRepository) SearchByTitle(_ context.Context, title string, page Page) ([]Album, error) {
+	return paginate(r.match(titleContains(title)), page), nil
+}
+
+func (r *MemoryRepository) SearchByArtist(_ context.Context, artist string, page Page) ([]Album, error) {
+	return paginate(r.match(artistContains(artist)), page), nil
+}
+
+func (r *MemoryRepository) FullText(_ context.Context, query string, page Page) ([]Album, error) {
+	return paginate(r.match(hasWords(query)), page), nil
+}
+
+func (r *MemoryRepository) Count(context.Context) (int, error) {
+	return len(r.match(func(Album) bool { return true })), nil
+}
+
+func (r *MemoryRepository) CountByTitle(_ context.Context, title string) (int, error) {
+	return len(r.match(titleContains(title))), nil
+}
+
+func (r *MemoryRepository) CountByArtist(_ context.Context, artist string) (int, error) {
+	return len(r.match(artistContains(artist))), nil
+}
+
+func (r *MemoryRepository) CountFullText(_ context.Context, query string) (int, error) {
+	return len(r.match(hasWords(query))), nil
+}
+
+// match returns the albums keep accepts, ordered by ID.
+func (r *MemoryRepository) match(keep func(Album) bool) []Album {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	albums := make([]Album, 0)
+	for _, a := range r.albums {
+		if keep(a) {
+			albums = append(albums, a)
+		}
+	}
+	slices.SortFunc(albums, func(a, b Album) int { return cmp.Compare(a.ID, b.ID) })
+	return albums
+}
+
+func paginate(albums []Album, page Page) []Album {
+	start := min(max(page.Offset, 0), len(albums))
+	if page.After > 0 {
+		start, _ = slices.BinarySearchFunc(albums, page.After+1, func(a Album, id int64) int { return cmp.Compare(a.ID, id) })
+	}
+	end := len(albums)
+	if page.Limit > 0 {
+		end = min(start+page.Limit, end)
+	}
+	return albums[start:end]
+}
+
+func titleContains(s string) func(Album) bool {
+	s = strings.ToLower(s)
+	return func(a Album) bool { return strings.Contains(strings.ToLower(a.Title), s) }
+}
+
+func artistContains(s string) func(Album) bool {
+	s = strings.ToLower(s)
+	return func(a Album) bool { return strings.Contains(strings.ToLower(a.Artist), s) }
+}
+
+// hasWords approximates Postgres plainto_tsquery: every word of query must
+// appear as a word of the title or artist.
+func hasWords(query string) func(Album) bool {
+	words := strings.Fields(strings.ToLower(query))
+	return func(a Album) bool {
+		if len(words) == 0 {
+			return false
+		}
+		text := strings.Fields(strings.ToLower(a.Title + " " + a.Artist))
+		for _, w := range words {
+			if !slices.Contains(text, w) {
+				return false
+			}
+		}
+		return true
+	}
+}
@@ -0,0 +1,111 @@
+package album
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func ids(albums []Album) []int64 {
+	out := make([]int64, len(albums))
+	for i, a := range albums {
+		out[i] = a.ID
+	}
+	return out
+}
+
+func TestMemoryRepository(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryRepository(
+		Album{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699},
+		Album{ID: 2, Title: "Jeru", Artist: "Gerry Mulligan", Price: 1799},
+		Album{ID: 3, Title: "Sarah Vaughan and Clifford Brown", Artist: "Sarah Vaughan", Price: 3999},
+	)
+
+	created, err := r.Create(ctx, Album{Title: "Giant Steps", Artist: "John Coltrane", Price: 6399})
+	if err != nil || created.ID != 4 {
+		t.Fatalf("Create = %+v, %v, want ID 4", created, err)
+	}
+
+	page, _ := r.List(ctx, Page{Limit: 2, Offset: 1})
+	if got := ids(page); len(got) != 2 || got[0] != 2 || got[1] != 3 {
+		t.Errorf("List(limit 2, offset 1) = %v, want [2 3]", got)
+	}
+	if all, _ := r.List(ctx, Page{}); len(all) != 4 {
+		t.Errorf("List(no limit) = %d albums, want 4", len(all))
+	}
+	if past, _ := r.List(ctx, Page{Limit: 10, Offset: 10}); len(past) != 0 {
+		t.Errorf("List(offset past end) = %v, want none", ids(past))
+	}
+
+	byArtist, _ := r.SearchByArtist(ctx, "coltrane", Page{})
+	if got := ids(byArtist); len(got) != 2 || got[0] != 1 || got[1] != 4 {
+		t.Errorf("SearchByArtist(coltrane) = %v, want [1 4]", got)
+	}
+	if n, _ := r.CountByArtist(ctx, "coltrane"); n != 2 {
+		t.Errorf("CountByArtist(coltrane) = %d, want 2", n)
+	}
+	byTitle, _ := r.SearchByTitle(ctx, "TRAIN", Page{})
+	if got := ids(byTitle); len(got) != 1 || got[0] != 1 {
+		t.Errorf("SearchByTitle(TRAIN) = %v, want [1]", got)
+	}
+	text, _ := r.FullText(ctx, "sarah clifford", Page{})
+	if got := ids(text); len(got) != 1 || got[0] != 3 {
+		t.Errorf("FullText(sarah clifford) = %v, want [3]", got)
+	}
+	if n, _ := r.CountFullText(ctx, "cliff"); n != 0 {
+		t.Errorf("CountFullText(cliff) = %d, want 0: only whole words match", n)
+	}
+	// An empty result must encode as [] like the SQL stores', not null.
+	if none, _ := r.SearchByTitle(ctx, "no such album", Page{}); none == nil {
+		t.Error("SearchByTitle(no match) = nil, want an empty slice")
+	}
+
+	updated, err := r.Update(ctx, Album{ID: 2, Title: "Jeru", Artist: "Gerry Mulligan", Price: 1999})
+	if err != nil || updated.Price != 1999 || updated.Version != 2 {
+		t.Errorf("Update = %+v, %v, want price 19.99 at version 2", updated, err)
+	}
+	title := "Jeru (Remastered)"
+	patched, err := r.Patch(ctx, 2, 2, Patch{Title: &title})
+	if err != nil || patched != (Album{ID: 2, Title: title, Artist: "Gerry Mulligan", Price: 1999, Version: 3}) {
+		t.Errorf("Patch = %+v, %v, want only the title changed, at version 3", patched, err)
+	}
+	_, updateErr := r.Update(ctx, Album{ID: 2, Title: "x", Artist: "y", Price: 1, Version: 2})
+	_, patchErr := r.Patch(ctx, 2, 1, Patch{})
+	stale := map[string]error{
+		"Update": updateErr,
+		"Patch":  patchErr,
+		"Delete": r.Delete(ctx, 2, 2),
+	}
+	for op, err := range stale {
+		if !errors.Is(err, ErrVersionMismatch) {
+			t.Errorf("%s with a stale version = %v, want ErrVersionMismatch", op, err)
+		}
+	}
+	if a, _ := r.Get(ctx, 2); a.Title != title {
+		t.Errorf("album 2 = %+v, want it unchanged by stale changes", a)
+	}
+	if err := r.Delete(ctx, 2, 3); err != nil {
+		t.Errorf("Delete(2) = %v", err)
+	}
+	if n, _ := r.Count(ctx); n != 3 {
+		t.Errorf("Count after Delete = %d, want 3", n)
+	}
+	if after, _ := r.List(ctx, Page{Limit: 1, After: 1, Offset: 5}); len(after) != 1 || after[0].ID != 3 {
+		t.Errorf("List(limit 1, after deleted neighbour) = %v, want [3]: After ignores Offset", ids(after))
+	}
+	if after, _ := r.SearchByArtist(ctx, "coltrane", Page{After: 1}); len(after) != 1 || after[0].ID != 4 {
+		t.Errorf("SearchByArtist(coltrane, after 1) = %v, want [4]", ids(after))
+	}
+
+	for name, err := range map[string]error{
+		"Get":    func() error { _, err := r.Get(ctx, 2); return err }(),
+		"Update": func() error { _, err := r.Update(ctx, Album{ID: 99}); return err }(),
+		"Patch":  func() error { _, err := r.Patch(ctx, 99, 0, Patch{}); return err }(),
+		"Delete": r.Delete(ctx, 2, 0),
+	} {
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s(missing) = %v, want ErrNotFound", name, err)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package album
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidMoney is returned, wrapped with details, for amounts that are not
+// a plain decimal with at most two fractional digits.
+var ErrInvalidMoney = errors.New("invalid money amount")
+
+// Money is an amount in cents. It is exact, unlike a float, and is written to
+// JSON as a number with two decimals and to SQL as a DECIMAL string.
+type Money int64
+
+// maxMoneyDigits bounds the integer part so that the amount in cents fits in
+// an int64.
+const maxMoneyDigits = 16
+
+// ParseMoney parses amounts like "12", "12.5", "-0.99" or "+3.10".
+func ParseMoney(s string) (Money, error) {
+	text := strings.TrimSpace(s)
+	neg := false
+	if rest, ok := strings.CutPrefix(text, "-"); ok {
+		neg, text = true, rest
+	} else {
+		text = strings.TrimPrefix(text, "+")
+	}
+	units, frac, _ := strings.Cut(text, ".")
+	if units == "" && frac == "" || !digits(units) || !digits(frac) || len(units) > maxMoneyDigits {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	if len(frac) > 2 {
+		return 0, fmt.Errorf("%w: %q has more than two decimal places", ErrInvalidMoney, s)
+	}
+	var cents int64
+	if units != "" {
+		n, err := strconv.ParseInt(units, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+		}
+		cents = n * 100
+	}
+	if frac != "" {
+		n, _ := strconv.ParseInt(frac, 10, 64)
+		if len(frac) == 1 {
+			n *= 10
+		}
+		cents += n
+	}
+	if neg {
+		cents = -cents
+	}
+	return Money(cents), nil
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// FromFloat converts a float price, rounding to the nearest cent. It is meant
+// for legacy float columns and inputs only.
+func FromFloat(f float64) Money {
+	return Money(math.Round(f * 100))
+}
+
+// Float64 returns m in currency units, for display or legacy APIs.
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// String formats m with exactly two decimals, e.g. "12.50" or "-0.99".
+func (m Money) String() string {
+	sign := ""
+	cents := int64(m)
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// MarshalJSON writes m as a JSON number such as 12.50.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a string holding one, so clients
+// that sent prices as strings keep working. null leaves m unchanged.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	text := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		var err error
+		if text, err = strconv.Unquote(text); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidMoney, data)
+		}
+	}
+	v, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Scan reads a DECIMAL column, which drivers return as text, as well as
+// integer and float columns.
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return m.scanText(string(v))
+	case string:
+		return m.scanText(v)
+	case int64:
+		*m = Money(v * 100)
+	case float64:
+		*m = FromFloat(v)
+	case float32:
+		*m = FromFloat(float64(v))
+	case nil:
+		return errors.New("cannot scan NULL into Money")
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return nil
+}
+
+func (m *Money) scanText(s string) error {
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value writes m as a decimal string, which both Postgres and MySQL convert
+// to DECIMAL without rounding.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
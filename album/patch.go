@@ -0,0 +1,289 @@
+package album
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"reflect"
+	"strings"
+)
+
+// Media types of the patch documents ParsePatch accepts. Plain
+// application/json is read as a merge patch.
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+	// AcceptPatch is the value of an Accept-Patch header listing them.
+	AcceptPatch = MergePatchType + ", " + JSONPatchType
+)
+
+// MaxPatchBytes is the largest patch document the PATCH endpoints read. A
+// patch changes one album, so anything near it is not a real patch.
+const MaxPatchBytes = 64 << 10
+
+var (
+	// ErrUnsupportedPatch is returned by ParsePatch for a media type it does
+	// not know.
+	ErrUnsupportedPatch = errors.New("unsupported patch media type")
+	// ErrInvalidPatch is returned, wrapped with details, for a patch that is
+	// malformed or changes a field that cannot change.
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrTestFailed is returned when a JSON Patch test operation does not
+	// match the album.
+	ErrTestFailed = errors.New("patch test failed")
+)
+
+// Patch is a partial update of an album: nil fields keep their value. A
+// removed field is set to its zero value, which Validate reports.
+type Patch struct {
+	Title  *string
+	Artist *string
+	Price  *Money
+}
+
+// Apply returns a with the fields p sets.
+func (p Patch) Apply(a Album) Album {
+	if p.Title != nil {
+		a.Title = *p.Title
+	}
+	if p.Artist != nil {
+		a.Artist = *p.Artist
+	}
+	if p.Price != nil {
+		a.Price = *p.Price
+	}
+	return a
+}
+
+// Validate checks the fields p sets with the rules of Album.Validate.
+func (p Patch) Validate() error {
+	return validate(p.Title, p.Artist, p.Price)
+}
+
+// ParsePatch reads a patch document of the given Content-Type. A JSON Patch
+// applies to the album as it is now, which ParsePatch gets from current;
+// a merge patch does not need it.
+func ParsePatch(contentType string, data []byte, current func() (Album, error)) (Patch, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case MergePatchType, "application/json":
+		return MergePatch(data)
+	case JSONPatchType:
+		a, err := current()
+		if err != nil {
+			return Patch{}, err
+		}
+		return JSONPatch(data, a)
+	}
+	return Patch{}, fmt.Errorf("%w %q", ErrUnsupportedPatch, mediaType)
+}
+
+// ConditionalPatch reads a patch like ParsePatch and returns it with the
+// version to apply it at: that of an If-Match header, checked by
+// Precondition, or else for a JSON Patch the version of the album it was
+// worked out from. current is called at most once.
+func ConditionalPatch(ifMatch, contentType string, data []byte, current func() (Album, error)) (Patch, int64, error) {
+	var (
+		a       Album
+		fetched bool
+		err     error
+	)
+	once := func() (Album, error) {
+		if !fetched {
+			a, err = current()
+			fetched = true
+		}
+		return a, err
+	}
+	version, err := Precondition(ifMatch, once)
+	if err != nil {
+		return Patch{}, 0, err
+	}
+	p, err := ParsePatch(contentType, data, func() (Album, error) {
+		a, err := once()
+		version = cmp.Or(version, a.Version)
+		return a, err
+	})
+	return p, version, err
+}
+
+// MergePatch parses an RFC 7396 JSON Merge Patch: an object holding the new
+// value of every field to change, or null to remove it.
+func MergePatch(data []byte) (Patch, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil || doc == nil {
+		return Patch{}, fmt.Errorf("%w: a merge patch must be a JSON object", ErrInvalidPatch)
+	}
+	var p Patch
+	for name, value := range doc {
+		if err := p.set(name, value); err != nil {
+			return Patch{}, err
+		}
+	}
+	return p, nil
+}
+
+// set sets the field of p held by the member name of an album document.
+func (p *Patch) set(name string, value json.RawMessage) error {
+	null := string(value) == "null"
+	var err error
+	switch name {
+	case "title":
+		p.Title = new(string)
+		if !null {
+			err = json.Unmarshal(value, p.Title)
+		}
+	case "artist":
+		p.Artist = new(string)
+		if !null {
+			err = json.Unmarshal(value, p.Artist)
+		}
+	case "price":
+		// Money ignores null, so a removed price stays zero.
+		p.Price = new(Money)
+		err = json.Unmarshal(value, p.Price)
+	case "id", "created_at":
+		return fmt.Errorf("%w: %s cannot be changed", ErrInvalidPatch, name)
+	default:
+		return fmt.Errorf("%w: albums have no %s", ErrInvalidPatch, name)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidPatch, name, err)
+	}
+	return nil
+}
+
+// operation is one operation of a JSON Patch.
+type operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// JSONPatch applies the operations of an RFC 6902 JSON Patch to a and
+// returns a Patch of the fields they change. The operations apply in order
+// and all or none do: a failed test operation fails the whole patch with
+// ErrTestFailed.
+func JSONPatch(data []byte, a Album) (Patch, error) {
+	var ops []operation
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return Patch{}, fmt.Errorf("%w: a JSON Patch must be an array of operations", ErrInvalidPatch)
+	}
+	doc := make(map[string]json.RawMessage)
+	doc["id"], _ = json.Marshal(a.ID)
+	doc["title"], _ = json.Marshal(a.Title)
+	doc["artist"], _ = json.Marshal(a.Artist)
+	doc["price"], _ = json.Marshal(a.Price)
+
+	changed := make(map[string]bool)
+	for i, op := range ops {
+		if err := op.apply(doc, changed); err != nil {
+			return Patch{}, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	var p Patch
+	for name := range changed {
+		value, ok := doc[name]
+		if !ok {
+			value = json.RawMessage("null")
+		}
+		if err := p.set(name, value); err != nil {
+			return Patch{}, err
+		}
+	}
+	return p, nil
+}
+
+// apply performs op on doc, the members of an album, recording in changed
+// the members it adds, replaces or removes.
+func (op operation) apply(doc map[string]json.RawMessage, changed map[string]bool) error {
+	name, err := member(op.Path)
+	if err != nil {
+		return err
+	}
+	_, exists := doc[name]
+	switch op.Op {
+	case "add", "replace":
+		if op.Value == nil {
+			return fmt.Errorf("%w: %s needs a value", ErrInvalidPatch, op.Op)
+		}
+		if op.Op == "replace" && !exists {
+			return fmt.Errorf("%w: %s was removed", ErrInvalidPatch, op.Path)
+		}
+		doc[name] = op.Value
+	case "remove":
+		if !exists {
+			return fmt.Errorf("%w: %s was removed", ErrInvalidPatch, op.Path)
+		}
+		delete(doc, name)
+	case "copy", "move":
+		from, err := member(op.From)
+		if err != nil {
+			return err
+		}
+		value, ok := doc[from]
+		if !ok {
+			return fmt.Errorf("%w: %s was removed", ErrInvalidPatch, op.From)
+		}
+		if op.Op == "move" && from != name {
+			delete(doc, from)
+			changed[from] = true
+		}
+		doc[name] = value
+	case "test":
+		if !exists {
+			return fmt.Errorf("%w: %s was removed", ErrTestFailed, op.Path)
+		}
+		same, err := sameValue(name, doc[name], op.Value)
+		if err != nil {
+			return err
+		}
+		if !same {
+			return fmt.Errorf("%w: %s", ErrTestFailed, op.Path)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+	}
+	changed[name] = true
+	return nil
+}
+
+// member returns the name of the album member that the JSON Pointer path
+// refers to. Albums are flat, so only paths like "/title" are valid.
+func member(path string) (string, error) {
+	name, ok := strings.CutPrefix(path, "/")
+	if !ok || strings.Contains(name, "/") {
+		return "", fmt.Errorf("%w: path %q is not an album field", ErrInvalidPatch, path)
+	}
+	name = strings.NewReplacer("~1", "/", "~0", "~").Replace(name)
+	switch name {
+	case "id", "title", "artist", "price":
+		return name, nil
+	}
+	return "", fmt.Errorf("%w: albums have no %s", ErrInvalidPatch, name)
+}
+
+// sameValue compares two values of the member name by what they decode to,
+// so that a price of 12.5 matches "12.50".
+func sameValue(name string, a, b json.RawMessage) (bool, error) {
+	if name == "id" {
+		var x, y int64
+		if err := json.Unmarshal(b, &y); err != nil {
+			return false, fmt.Errorf("%w: id: %w", ErrInvalidPatch, err)
+		}
+		json.Unmarshal(a, &x)
+		return x == y, nil
+	}
+	var p, q Patch
+	if err := p.set(name, a); err != nil {
+		return false, err
+	}
+	if err := q.set(name, b); err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(p, q), nil
+}
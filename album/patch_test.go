@@ -0,0 +1,109 @@
+package album
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	p, err := MergePatch([]byte(`{"title":"Giant Steps","price":"63.99"}`))
+	if err != nil || p.Artist != nil {
+		t.Fatalf("MergePatch = %+v, %v, want title and price only", p, err)
+	}
+	got := p.Apply(Album{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699})
+	if got != (Album{ID: 1, Title: "Giant Steps", Artist: "John Coltrane", Price: 6399}) {
+		t.Errorf("Apply = %+v, want the new title and price", got)
+	}
+
+	p, err = MergePatch([]byte(`{"artist":null,"price":null}`))
+	var invalid *ValidationError
+	if err != nil || !errors.As(p.Validate(), &invalid) || len(invalid.Errors) != 2 {
+		t.Errorf("MergePatch removing artist and price = %+v, %v, want both to fail validation", p, err)
+	}
+
+	for _, doc := range []string{`[]`, `null`, `{"id":3}`, `{"genre":"jazz"}`, `{"title":7}`, `{"price":"1.005"}`} {
+		if _, err := MergePatch([]byte(doc)); !errors.Is(err, ErrInvalidPatch) {
+			t.Errorf("MergePatch(%s) = %v, want ErrInvalidPatch", doc, err)
+		}
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	a := Album{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699}
+	p, err := JSONPatch([]byte(`[
+		{"op":"test","path":"/price","value":"56.99"},
+		{"op":"test","path":"/id","value":1},
+		{"op":"replace","path":"/price","value":49.5},
+		{"op":"copy","from":"/artist","path":"/title"}
+	]`), a)
+	if err != nil || p.Artist != nil {
+		t.Fatalf("JSONPatch = %+v, %v, want title and price only", p, err)
+	}
+	if got := p.Apply(a); got.Title != "John Coltrane" || got.Price != 4950 {
+		t.Errorf("Apply = %+v, want the artist as title and price 49.50", got)
+	}
+
+	p, err = JSONPatch([]byte(`[{"op":"move","from":"/title","path":"/artist"}]`), a)
+	if err != nil || p.Title == nil || *p.Title != "" || *p.Artist != "Blue Train" {
+		t.Errorf("JSONPatch(move) = %+v, %v, want the title moved to the artist", p, err)
+	}
+
+	tests := map[string]error{
+		`[{"op":"test","path":"/title","value":"Jeru"}]`:                             ErrTestFailed,
+		`[{"op":"remove","path":"/title"},{"op":"test","path":"/title","value":""}]`: ErrTestFailed,
+		`{"op":"remove","path":"/title"}`:                                            ErrInvalidPatch,
+		`[{"op":"replace","path":"/id","value":2}]`:                                  ErrInvalidPatch,
+		`[{"op":"add","path":"/genre","value":"jazz"}]`:                              ErrInvalidPatch,
+		`[{"op":"add","path":"/title"}]`:                                             ErrInvalidPatch,
+		`[{"op":"replace","path":"/title/0","value":"x"}]`:                           ErrInvalidPatch,
+		`[{"op":"remove","path":"/title"},{"op":"remove","path":"/title"}]`:          ErrInvalidPatch,
+		`[{"op":"copy","from":"/price","path":"/title"}]`:                            ErrInvalidPatch,
+		`[{"op":"swap","path":"/title"}]`:                                            ErrInvalidPatch,
+	}
+	for doc, want := range tests {
+		if _, err := JSONPatch([]byte(doc), a); !errors.Is(err, want) {
+			t.Errorf("JSONPatch(%s) = %v, want %v", doc, err, want)
+		}
+	}
+}
+
+func TestParsePatch(t *testing.T) {
+	errGone := errors.New("album gone")
+	current := func() (Album, error) { return Album{}, errGone }
+	if _, err := ParsePatch("application/merge-patch+json; charset=utf-8", []byte(`{"title":"x"}`), current); err != nil {
+		t.Errorf("ParsePatch(merge patch) = %v, want it not to need the album", err)
+	}
+	if _, err := ParsePatch(JSONPatchType, []byte(`[]`), current); !errors.Is(err, errGone) {
+		t.Errorf("ParsePatch(JSON Patch) = %v, want the error of current", err)
+	}
+	if _, err := ParsePatch("text/plain", nil, current); !errors.Is(err, ErrUnsupportedPatch) {
+		t.Errorf("ParsePatch(text/plain) = %v, want ErrUnsupportedPatch", err)
+	}
+}
+
+func TestConditionalPatch(t *testing.T) {
+	var fetches int
+	current := func() (Album, error) {
+		fetches++
+		return Album{ID: 1, Title: "Jeru", Version: 3}, nil
+	}
+	tests := []struct {
+		ifMatch, contentType, doc string
+		version                   int64
+		fetches                   int
+		err                       error
+	}{
+		{``, MergePatchType, `{"title":"x"}`, 0, 0, nil},
+		{`"3"`, MergePatchType, `{"title":"x"}`, 3, 1, nil},
+		{``, JSONPatchType, `[{"op":"replace","path":"/title","value":"x"}]`, 3, 1, nil},
+		{`"3"`, JSONPatchType, `[{"op":"replace","path":"/title","value":"x"}]`, 3, 1, nil},
+		{`"2"`, JSONPatchType, `[]`, 0, 1, ErrVersionMismatch},
+	}
+	for _, tt := range tests {
+		fetches = 0
+		_, version, err := ConditionalPatch(tt.ifMatch, tt.contentType, []byte(tt.doc), current)
+		if version != tt.version || fetches != tt.fetches || !errors.Is(err, tt.err) {
+			t.Errorf("ConditionalPatch(%s, %s) = %d, %v after %d fetches, want %d, %v after %d", tt.ifMatch, tt.contentType, version, err, fetches, tt.version, tt.err, tt.fetches)
+		}
+	}
+}
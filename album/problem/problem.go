@@ -0,0 +1,150 @@
+// Package problem describes the errors of the album services as RFC 7807
+// problem details, sent as application/problem+json.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"dev.mfr/album"
+)
+
+// ContentType is the media type of a problem response.
+const ContentType = "application/problem+json"
+
+// Code identifies a kind of problem. Codes are stable, so clients can
+// branch on them rather than on the title or detail.
+type Code string
+
+const (
+	InvalidID         Code = "invalid_id"
+	InvalidCursor     Code = "invalid_cursor"
+	InvalidPagination Code = "invalid_pagination"
+	MissingParameter  Code = "missing_parameter"
+	// InvalidParameter is a query parameter with a value the endpoint does
+	// not accept, such as an unknown export format.
+	InvalidParameter Code = "invalid_parameter"
+	// InvalidBody is a request body that is not an album at all; see
+	// ValidationFailed for an album with invalid fields.
+	InvalidBody      Code = "invalid_body"
+	ValidationFailed Code = "validation_failed"
+	// BodyTooLarge is an import over bulk.MaxImportBytes or a patch over
+	// album.MaxPatchBytes.
+	BodyTooLarge Code = "body_too_large"
+	NotFound     Code = "not_found"
+	// Conflict, ConstraintViolation and Unavailable report
+	// album.ErrConflict, ErrConstraint and ErrUnavailable.
+	Conflict            Code = "conflict"
+	ConstraintViolation Code = "constraint_violation"
+	Unavailable         Code = "unavailable"
+	// UnsupportedMediaType and PatchTestFailed report album.ErrUnsupportedPatch
+	// and ErrTestFailed.
+	UnsupportedMediaType Code = "unsupported_media_type"
+	PatchTestFailed      Code = "patch_test_failed"
+	// PreconditionFailed is an If-Match header that does not match the
+	// album, or album.ErrVersionMismatch.
+	PreconditionFailed Code = "precondition_failed"
+	Internal           Code = "internal_error"
+)
+
+// Problem is an RFC 7807 problem details object. Code, RequestID and
+// Errors are extension members.
+type Problem struct {
+	// Type is always "about:blank", so Title is the status text; Code tells
+	// problems with the same status apart.
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Instance is the path of the request.
+	Instance  string `json:"instance,omitempty"`
+	Code      Code   `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+	// Errors lists the invalid fields of a ValidationFailed problem.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is one invalid field of a ValidationFailed problem.
+type FieldError struct {
+	Field string `json:"field"`
+	// Code is required, too_long, not_positive, too_large or invalid.
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// New returns a problem with the given status, code and detail.
+func New(status int, code Code, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// InternalError returns the problem for an unexpected failure. Its detail
+// says nothing of the cause, which belongs in the server log only.
+func InternalError() *Problem {
+	return New(http.StatusInternalServerError, Internal, "The server failed to handle the request")
+}
+
+// fieldCodes maps the validation errors of package album to FieldError
+// codes.
+var fieldCodes = []struct {
+	err  error
+	code string
+}{
+	{album.ErrRequired, "required"},
+	{album.ErrTooLong, "too_long"},
+	{album.ErrNotPositive, "not_positive"},
+	{album.ErrTooLarge, "too_large"},
+}
+
+// Validation returns the problem for err, an error from album.Validate,
+// with a FieldError for every invalid field.
+func Validation(err error) *Problem {
+	p := New(http.StatusBadRequest, ValidationFailed, err.Error())
+	var invalid *album.ValidationError
+	if !errors.As(err, &invalid) {
+		return p
+	}
+	for _, fe := range invalid.Errors {
+		code := "invalid"
+		for _, fc := range fieldCodes {
+			if errors.Is(fe.Err, fc.err) {
+				code = fc.code
+				break
+			}
+		}
+		p.Errors = append(p.Errors, FieldError{Field: fe.Field, Code: code, Detail: fe.Error()})
+	}
+	return p
+}
+
+// Patch returns the problem for err, an error from album.ParsePatch: 415 for
+// an unsupported media type, 409 for a failed test operation or 400 for an
+// invalid patch. It returns nil for other errors, such as those of the
+// album store.
+func Patch(err error) *Problem {
+	switch {
+	case errors.Is(err, album.ErrUnsupportedPatch):
+		return New(http.StatusUnsupportedMediaType, UnsupportedMediaType, err.Error())
+	case errors.Is(err, album.ErrTestFailed):
+		return New(http.StatusConflict, PatchTestFailed, err.Error())
+	case errors.Is(err, album.ErrInvalidPatch):
+		return New(http.StatusBadRequest, InvalidBody, err.Error())
+	}
+	return nil
+}
+
+// Write sends p as the response to r, filling in Instance from its path and
+// RequestID from requestID.
+func (p *Problem) Write(w http.ResponseWriter, r *http.Request, requestID string) {
+	p.Instance = r.URL.Path
+	p.RequestID = requestID
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
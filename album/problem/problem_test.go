@@ -0,0 +1,81 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dev.mfr/album"
+)
+
+func TestValidation(t *testing.T) {
+	err := album.Album{Artist: "John Coltrane", Price: album.MaxPrice + 1}.Validate()
+	p := Validation(err)
+	if p.Status != http.StatusBadRequest || p.Code != ValidationFailed {
+		t.Errorf("Validation = %d %s, want 400 %s", p.Status, p.Code, ValidationFailed)
+	}
+	want := []FieldError{
+		{Field: "title", Code: "required", Detail: "title is required"},
+		{Field: "price", Code: "too_large", Detail: "price is too large (maximum 99999999.99)"},
+	}
+	if len(p.Errors) != len(want) || p.Errors[0] != want[0] || p.Errors[1] != want[1] {
+		t.Errorf("Validation errors = %+v, want %+v", p.Errors, want)
+	}
+
+	if p := Validation(errors.New("odd")); len(p.Errors) != 0 || p.Detail != "odd" {
+		t.Errorf("Validation(plain error) = %+v, want no field errors", p)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   Code
+	}{
+		{fmt.Errorf("%w \"text/plain\"", album.ErrUnsupportedPatch), http.StatusUnsupportedMediaType, UnsupportedMediaType},
+		{fmt.Errorf("operation 0: %w: /title", album.ErrTestFailed), http.StatusConflict, PatchTestFailed},
+		{fmt.Errorf("%w: albums have no genre", album.ErrInvalidPatch), http.StatusBadRequest, InvalidBody},
+	}
+	for _, tt := range tests {
+		if p := Patch(tt.err); p == nil || p.Status != tt.status || p.Code != tt.code {
+			t.Errorf("Patch(%v) = %+v, want %d %s", tt.err, p, tt.status, tt.code)
+		}
+	}
+	if p := Patch(album.ErrNotFound); p != nil {
+		t.Errorf("Patch(ErrNotFound) = %+v, want nil", p)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	rec := httptest.NewRecorder()
+	New(http.StatusNotFound, NotFound, "Album 7 not found").Write(rec, httptest.NewRequest(http.MethodGet, "/albums/7?x=1", nil), "req-1")
+
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, ContentType)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"type":       "about:blank",
+		"title":      "Not Found",
+		"status":     float64(404),
+		"detail":     "Album 7 not found",
+		"instance":   "/albums/7",
+		"code":       "not_found",
+		"request_id": "req-1",
+	}
+	if rec.Code != http.StatusNotFound || len(got) != len(want) {
+		t.Fatalf("Write = %d %v, want 404 %v", rec.Code, got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %v, want %v", k, got[k], v)
+		}
+	}
+}
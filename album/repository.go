@@ -0,0 +1,76 @@
+package album
+
+import (
+	"context"
+	"errors"
+)
+
+// Errors a repository returns, possibly wrapped, for the failures a client
+// can act on. Check for them with errors.Is.
+var (
+	// ErrNotFound means no album has the given ID.
+	ErrNotFound = errors.New("album not found")
+	// ErrConflict means the change would duplicate a unique value.
+	ErrConflict = errors.New("album conflicts with an existing one")
+	// ErrConstraint means the backend rejected the change because it breaks
+	// one of its constraints, such as a CHECK or a column limit.
+	ErrConstraint = errors.New("album violates a constraint")
+	// ErrUnavailable means the backend could not be reached; the same
+	// request may succeed later.
+	ErrUnavailable = errors.New("album store unavailable")
+	// ErrVersionMismatch means the album changed since the version a
+	// conditional Update, Patch or Delete expected.
+	ErrVersionMismatch = errors.New("album changed since the expected version")
+)
+
+// Page selects part of a listing, ordered by ID. A Limit of zero or less
+// means no limit.
+type Page struct {
+	Limit  int
+	Offset int
+	// After, if positive, starts the page at the first album with a larger
+	// ID instead of skipping Offset albums; see Cursor.
+	After int64
+}
+
+// AlbumRepository stores albums. Implementations return ErrNotFound,
+// possibly wrapped, for a missing ID.
+//
+// Every change increments the Version of an album. Update, Patch and Delete
+// are conditional when given a non-zero version: they change nothing and
+// return ErrVersionMismatch unless the album still has that version.
+type AlbumRepository interface {
+	Get(ctx context.Context, id int64) (Album, error)
+	List(ctx context.Context, page Page) ([]Album, error)
+	// Create stores a new album and returns it with its ID set.
+	Create(ctx context.Context, a Album) (Album, error)
+	// Update replaces the album with a.ID, if it has a.Version.
+	Update(ctx context.Context, a Album) (Album, error)
+	// Patch changes the fields of the album with the given ID that p sets
+	// and returns the result.
+	Patch(ctx context.Context, id, version int64, p Patch) (Album, error)
+	Delete(ctx context.Context, id, version int64) error
+	// SearchByTitle and SearchByArtist match a case-insensitive substring.
+	SearchByTitle(ctx context.Context, title string, page Page) ([]Album, error)
+	SearchByArtist(ctx context.Context, artist string, page Page) ([]Album, error)
+	// FullText matches albums whose title and artist contain the words of
+	// query.
+	FullText(ctx context.Context, query string, page Page) ([]Album, error)
+}
+
+// Counter is implemented by repositories that can count the albums a
+// listing would return across all pages.
+type Counter interface {
+	Count(ctx context.Context) (int, error)
+	CountByTitle(ctx context.Context, title string) (int, error)
+	CountByArtist(ctx context.Context, artist string) (int, error)
+	CountFullText(ctx context.Context, query string) (int, error)
+}
+
+// BulkCreator is implemented by repositories that can store many albums at
+// once.
+type BulkCreator interface {
+	// CreateAll stores albums in one transaction, so either all or none of
+	// them are stored, and returns them with their IDs set.
+	CreateAll(ctx context.Context, albums []Album) ([]Album, error)
+}